@@ -0,0 +1,156 @@
+package book
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrEmptyAddress is returned for an address without any field filled in.
+var ErrEmptyAddress = errors.New("address is empty")
+
+// Address is one postal address of a contact. Street may hold several
+// lines separated by line breaks.
+type Address struct {
+	Label      string `json:"label,omitempty"`
+	Street     string `json:"street,omitempty"`
+	City       string `json:"city,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postalCode,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// NewAddress trims and validates the fields of an address. At least one of
+// them besides the label must be given.
+func NewAddress(label, street, city, region, postalCode, country string) (Address, error) {
+	a := Address{
+		Label:      strings.ToLower(strings.TrimSpace(label)),
+		Street:     trimLines(street),
+		City:       strings.TrimSpace(city),
+		Region:     strings.TrimSpace(region),
+		PostalCode: strings.TrimSpace(postalCode),
+		Country:    strings.TrimSpace(country),
+	}
+	if a.Label != "" && !validLabel(a.Label) {
+		return Address{}, fmt.Errorf("invalid label %q, use letters, digits and dashes", label)
+	}
+	if a.IsEmpty() {
+		return Address{}, ErrEmptyAddress
+	}
+	return a, nil
+}
+
+// ParseAddress reads an address written as
+// "[label:]street|city|region|postal code|country", for example
+// "home:12 Palm Street|Dubai||00000|AE". Trailing fields may be left out.
+// A backslash keeps the character after it in the field, so String writes
+// a ";", "|" or ":" of a field as "\;", "\|" or "\:".
+func ParseAddress(input string) (Address, error) {
+	fields := splitEscaped(input, '|')
+	if len(fields) > 5 {
+		return Address{}, fmt.Errorf("address %q has more than 5 fields", input)
+	}
+	for len(fields) < 5 {
+		fields = append(fields, "")
+	}
+	// a word before a colon in the street is the label; anything else, such
+	// as "Unit 4: Tower B", is part of the street
+	var label string
+	if parts := splitEscaped(fields[0], ':'); len(parts) > 1 && validLabel(strings.ToLower(strings.TrimSpace(parts[0]))) {
+		label, fields[0] = parts[0], strings.Join(parts[1:], ":")
+	}
+	for i := range fields {
+		fields[i] = unescapeAddress(fields[i])
+	}
+	return NewAddress(label, fields[0], fields[1], fields[2], fields[3], fields[4])
+}
+
+// String renders the address as ParseAddress reads it, with the
+// separators a field holds escaped.
+func (a Address) String() string {
+	fields := []string{a.Street, a.City, a.Region, a.PostalCode, a.Country}
+	for i, f := range fields {
+		fields[i] = addressEscaper.Replace(f)
+	}
+	s := strings.Join(fields, "|")
+	if a.Label != "" {
+		s = a.Label + ":" + s
+	}
+	return s
+}
+
+// Lines returns the address as written on an envelope: the street lines,
+// then the city, region and postal code, then the country.
+func (a Address) Lines() []string {
+	var lines []string
+	for _, line := range strings.Split(a.Street, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	place := strings.TrimSpace(strings.Join(nonEmpty(a.City, strings.TrimSpace(a.Region+" "+a.PostalCode)), ", "))
+	return append(lines, nonEmpty(place, a.Country)...)
+}
+
+// IsEmpty reports whether no field besides the label is filled in.
+func (a Address) IsEmpty() bool {
+	return a.Street == "" && a.City == "" && a.Region == "" && a.PostalCode == "" && a.Country == ""
+}
+
+// Matches reports whether query is part of one of the address fields,
+// ignoring case.
+func (a Address) Matches(query string) bool {
+	q := strings.ToLower(query)
+	for _, field := range []string{a.Street, a.City, a.Region, a.PostalCode, a.Country} {
+		if strings.Contains(strings.ToLower(field), q) {
+			return true
+		}
+	}
+	return false
+}
+
+// JoinAddresses renders addresses as one semicolon separated list.
+func JoinAddresses(addresses []Address) string {
+	parts := make([]string, len(addresses))
+	for i, a := range addresses {
+		parts[i] = a.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// addressEscaper escapes the characters separating addresses, their
+// fields and their label.
+var addressEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, "|", `\|`, ":", `\:`)
+
+// unescapeAddress removes the backslashes String adds.
+func unescapeAddress(field string) string {
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] == '\\' && i+1 < len(field) {
+			i++
+		}
+		b.WriteByte(field[i])
+	}
+	return b.String()
+}
+
+// trimLines trims every line of text and drops the empty ones.
+func trimLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func nonEmpty(values ...string) []string {
+	var kept []string
+	for _, v := range values {
+		if v != "" {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
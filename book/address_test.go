@@ -0,0 +1,78 @@
+package book
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Address
+	}{
+		{"home:12 Palm Street|Dubai||00000|AE", Address{Label: "home", Street: "12 Palm Street", City: "Dubai", PostalCode: "00000", Country: "AE"}},
+		{"12 Palm Street|Dubai", Address{Street: "12 Palm Street", City: "Dubai"}},
+		{"Unit 4: Tower B|Dubai", Address{Street: "Unit 4: Tower B", City: "Dubai"}},
+		{`Tower\:5|Dubai`, Address{Street: "Tower:5", City: "Dubai"}},
+		{`work:Suite 5\; Floor 2\|x|Paris||75001|France`, Address{Label: "work", Street: "Suite 5; Floor 2|x", City: "Paris", PostalCode: "75001", Country: "France"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseAddress(tt.input)
+		if err != nil {
+			t.Errorf("ParseAddress(%q): %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAddress(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+	for _, input := range []string{"", "|||", "a|b|c|d|e|f"} {
+		if got, err := ParseAddress(input); err == nil {
+			t.Errorf("ParseAddress(%q) = %+v, want an error", input, got)
+		}
+	}
+}
+
+func TestAddressStringRoundTrip(t *testing.T) {
+	addresses := []Address{
+		{Label: "work", Street: "Suite 5; Floor 2|x", City: "Paris", PostalCode: "75001", Country: "France"},
+		{Street: "home:not a label", City: "Back\\slash"},
+		{Label: "home", Street: "12 Palm Street\nApt 4", City: "Dubai", Country: "AE"},
+	}
+	entries := SplitEntries(JoinAddresses(addresses))
+	if len(entries) != len(addresses) {
+		t.Fatalf("JoinAddresses gave %d entries, want %d: %q", len(entries), len(addresses), entries)
+	}
+	for i, entry := range entries {
+		got, err := ParseAddress(entry)
+		if err != nil {
+			t.Errorf("ParseAddress(%q): %v", entry, err)
+			continue
+		}
+		if got != addresses[i] {
+			t.Errorf("ParseAddress(%q) = %+v, want %+v", entry, got, addresses[i])
+		}
+	}
+}
+
+func TestVCardAddressSurvivesCSV(t *testing.T) {
+	card := "BEGIN:VCARD\r\nVERSION:3.0\r\nFN:Jean Dupont\r\n" +
+		`ADR;TYPE=work:;;Suite 5\; Floor 2|x;Paris;;75001;France` + "\r\nEND:VCARD\r\n"
+	contacts, rejected, err := ReadVCards(strings.NewReader(card), "FR")
+	if err != nil || len(rejected) > 0 || len(contacts) != 1 {
+		t.Fatalf("ReadVCards = %v, %v, %v", contacts, rejected, err)
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, contacts); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 || !reflect.DeepEqual(read[0].Addresses, contacts[0].Addresses) {
+		t.Errorf("addresses after CSV = %+v, want %+v", read[0].Addresses, contacts[0].Addresses)
+	}
+}
@@ -0,0 +1,217 @@
+// Package book owns the contact model, its validation and normalization, and
+// the persistence of the address book. It is shared by the command-line
+// program and the Bubble Tea front-end so both behave the same way.
+package book
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Errors returned when a contact fails validation.
+var (
+	ErrEmptyName    = errors.New("name is required")
+	ErrInvalidEmail = errors.New("invalid email format")
+)
+
+// Contact is a single entry of the address book.
+type Contact struct {
+	// ID is a UUID assigned when the contact is added. It never changes.
+	ID string `json:"id"`
+	// Name is the full name, given name first. Contacts saved before names
+	// were structured have it only, with NameParts left empty.
+	Name      string    `json:"name"`
+	NameParts NameParts `json:"nameParts,omitzero"`
+	Emails    []Email   `json:"emails,omitempty"`
+	Phones    []Phone   `json:"phones,omitempty"`
+	Addresses []Address `json:"addresses,omitempty"`
+
+	// Work details. ManagerID and AssistantID hold the IDs of other
+	// contacts of the book.
+	Organization string `json:"organization,omitempty"`
+	Department   string `json:"department,omitempty"`
+	Title        string `json:"title,omitempty"`
+	ManagerID    string `json:"managerId,omitempty"`
+	AssistantID  string `json:"assistantId,omitempty"`
+
+	// Tags put the contact in groups, see Tags. Favorite contacts are
+	// listed first.
+	Tags     []string `json:"tags,omitempty"`
+	Favorite bool     `json:"favorite,omitempty"`
+
+	// Events are the birthday, anniversary and other dates celebrated
+	// every year.
+	Events []Event `json:"events,omitempty"`
+
+	// Notes is free text, possibly several lines long. Interactions is the
+	// log of calls, meetings and messages, oldest first.
+	Notes        string        `json:"notes,omitempty"`
+	Interactions []Interaction `json:"interactions,omitempty"`
+
+	// Fields holds the values of the custom fields declared in a Schema,
+	// by field name.
+	Fields map[string]string `json:"fields,omitempty"`
+
+	// Created and Updated are the times the contact was added and last
+	// changed, and Modified the time each field last changed, by field
+	// name such as "emails" or "fields.slack". The store maintains them;
+	// they are zero for contacts saved before changes were recorded.
+	Created  time.Time            `json:"created,omitzero"`
+	Updated  time.Time            `json:"updated,omitzero"`
+	Modified map[string]time.Time `json:"modified,omitempty"`
+
+	// Version identifies the contact as it was stored when it was read.
+	// The store sets it on every contact it returns and refuses to update
+	// or delete a contact that was changed by someone else since. It is
+	// not saved.
+	Version string `json:"-"`
+}
+
+// NewContact validates raw user input and returns the normalized contact.
+// The name is capitalized and split into its parts with SetName. Emails and
+// phones are given in the form accepted by ParseEmail and ParsePhoneEntry;
+// numbers lacking a country code are read as numbers of region.
+func NewContact(name string, emails, phones []string, region string) (Contact, error) {
+	var c Contact
+	c.SetName(name)
+	if c.Name == "" {
+		return Contact{}, ErrEmptyName
+	}
+	for _, input := range emails {
+		email, err := ParseEmail(input)
+		if err != nil {
+			return Contact{}, err
+		}
+		c.Emails = append(c.Emails, email)
+	}
+	for _, input := range phones {
+		phone, err := ParsePhoneEntry(input, region)
+		if err != nil {
+			return Contact{}, err
+		}
+		c.Phones = append(c.Phones, phone)
+	}
+	c.Normalize()
+	return c, nil
+}
+
+// Normalize makes sure exactly one email and one phone are marked primary:
+// the first one marked wins, and the first entry is used when none is.
+func (c *Contact) Normalize() {
+	primary := -1
+	for i := range c.Emails {
+		if c.Emails[i].Primary && primary < 0 {
+			primary = i
+		}
+		c.Emails[i].Primary = false
+	}
+	if len(c.Emails) > 0 {
+		c.Emails[max(primary, 0)].Primary = true
+	}
+
+	primary = -1
+	for i := range c.Phones {
+		if c.Phones[i].Primary && primary < 0 {
+			primary = i
+		}
+		c.Phones[i].Primary = false
+	}
+	if len(c.Phones) > 0 {
+		c.Phones[max(primary, 0)].Primary = true
+	}
+}
+
+// AddEmail appends email. When it is marked primary it takes over from the
+// current primary email.
+func (c *Contact) AddEmail(email Email) {
+	if email.Primary {
+		for i := range c.Emails {
+			c.Emails[i].Primary = false
+		}
+	}
+	c.Emails = append(c.Emails, email)
+}
+
+// AddPhone appends phone. When it is marked primary it takes over from the
+// current primary phone.
+func (c *Contact) AddPhone(phone Phone) {
+	if phone.Primary {
+		for i := range c.Phones {
+			c.Phones[i].Primary = false
+		}
+	}
+	c.Phones = append(c.Phones, phone)
+}
+
+// PrimaryEmail returns the address of the primary email, or "".
+func (c Contact) PrimaryEmail() string {
+	for _, e := range c.Emails {
+		if e.Primary {
+			return e.Address
+		}
+	}
+	if len(c.Emails) > 0 {
+		return c.Emails[0].Address
+	}
+	return ""
+}
+
+// PrimaryPhone returns the number of the primary phone, or "".
+func (c Contact) PrimaryPhone() string {
+	for _, p := range c.Phones {
+		if p.Primary {
+			return p.Number
+		}
+	}
+	if len(c.Phones) > 0 {
+		return c.Phones[0].Number
+	}
+	return ""
+}
+
+// Matches reports whether query is part of the contact name, nickname,
+// organization, department, title or notes, of one of its tags, custom
+// fields, email addresses or postal addresses, ignoring case, or whether
+// its digits are part of one of its phone numbers.
+func (c Contact) Matches(query string) bool {
+	q := strings.ToLower(query)
+	for _, field := range []string{c.Name, c.NameParts.Nickname, c.Organization, c.Department, c.Title, c.Notes} {
+		if strings.Contains(strings.ToLower(field), q) {
+			return true
+		}
+	}
+	for _, e := range c.Emails {
+		if strings.Contains(strings.ToLower(e.Address), q) {
+			return true
+		}
+	}
+	for _, a := range c.Addresses {
+		if a.Matches(query) {
+			return true
+		}
+	}
+	for _, tag := range c.Tags {
+		if strings.Contains(strings.ToLower(tag), q) {
+			return true
+		}
+	}
+	for _, value := range c.Fields {
+		if strings.Contains(strings.ToLower(value), q) {
+			return true
+		}
+	}
+	digits, _, err := phoneDigits(query)
+	if err != nil || len(digits) < 3 {
+		return false
+	}
+	// a national number typed with its trunk 0 still finds the E.164 entry
+	national := strings.TrimLeft(digits, "0")
+	for _, p := range c.Phones {
+		stored := strings.TrimPrefix(p.Number, "+")
+		if strings.Contains(stored, digits) || national != "" && strings.Contains(stored, national) {
+			return true
+		}
+	}
+	return false
+}
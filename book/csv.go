@@ -0,0 +1,286 @@
+package book
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvColumn ties a CSV column to the Contact field it holds.
+type csvColumn struct {
+	name string
+	get  func(Contact) string
+	set  func(*Contact, string)
+}
+
+// csvColumns are written, in this order, by WriteCSV. Lists such as emails
+// and phones are stored in one cell, separated by semicolons.
+var csvColumns = []csvColumn{
+	{"ID", func(c Contact) string { return c.ID }, func(c *Contact, v string) { c.ID = v }},
+	{"Name", func(c Contact) string { return c.Name }, func(c *Contact, v string) { c.Name = v }},
+	{"Prefix", func(c Contact) string { return c.NameParts.Prefix }, func(c *Contact, v string) { c.NameParts.Prefix = v }},
+	{"Given Name", func(c Contact) string { return c.NameParts.Given }, func(c *Contact, v string) { c.NameParts.Given = v }},
+	{"Middle Name", func(c Contact) string { return c.NameParts.Middle }, func(c *Contact, v string) { c.NameParts.Middle = v }},
+	{"Family Name", func(c Contact) string { return c.NameParts.Family }, func(c *Contact, v string) { c.NameParts.Family = v }},
+	{"Suffix", func(c Contact) string { return c.NameParts.Suffix }, func(c *Contact, v string) { c.NameParts.Suffix = v }},
+	{"Nickname", func(c Contact) string { return c.NameParts.Nickname }, func(c *Contact, v string) { c.NameParts.Nickname = v }},
+	{"Emails", func(c Contact) string { return JoinEmails(c.Emails) }, func(c *Contact, v string) {
+		for _, entry := range SplitEntries(v) {
+			label, address, primary := readEntry(entry)
+			c.Emails = append(c.Emails, Email{Label: label, Address: address, Primary: primary})
+		}
+	}},
+	{"Phones", func(c Contact) string { return JoinPhones(c.Phones) }, func(c *Contact, v string) {
+		for _, entry := range SplitEntries(v) {
+			label, number, primary := readEntry(entry)
+			c.Phones = append(c.Phones, Phone{Label: label, Number: number, Primary: primary})
+		}
+	}},
+	{"Addresses", func(c Contact) string { return JoinAddresses(c.Addresses) }, func(c *Contact, v string) {
+		for _, entry := range SplitEntries(v) {
+			address, err := ParseAddress(entry)
+			if errors.Is(err, ErrEmptyAddress) {
+				continue
+			}
+			if err != nil {
+				address = Address{Street: entry}
+			}
+			c.Addresses = append(c.Addresses, address)
+		}
+	}},
+	{"Organization", func(c Contact) string { return c.Organization }, func(c *Contact, v string) { c.Organization = v }},
+	{"Department", func(c Contact) string { return c.Department }, func(c *Contact, v string) { c.Department = v }},
+	{"Title", func(c Contact) string { return c.Title }, func(c *Contact, v string) { c.Title = v }},
+	{"Manager", func(c Contact) string { return c.ManagerID }, func(c *Contact, v string) { c.ManagerID = v }},
+	{"Assistant", func(c Contact) string { return c.AssistantID }, func(c *Contact, v string) { c.AssistantID = v }},
+	{"Tags", func(c Contact) string { return JoinTags(c.Tags) }, func(c *Contact, v string) {
+		for _, tag := range SplitEntries(v) {
+			c.AddTag(tag)
+		}
+	}},
+	{"Events", func(c Contact) string { return JoinEvents(c.Events) }, nil},
+	// notes and the interaction log span several lines of a quoted cell
+	{"Notes", func(c Contact) string { return c.Notes }, func(c *Contact, v string) { c.Notes = NormalizeNotes(v) }},
+	{"Interactions", func(c Contact) string { return JoinInteractions(c.Interactions) }, nil},
+	{"Favorite", func(c Contact) string {
+		if c.Favorite {
+			return "yes"
+		}
+		return ""
+	}, func(c *Contact, v string) { c.Favorite = strings.EqualFold(v, "yes") || strings.EqualFold(v, "true") }},
+	{"Created", func(c Contact) string { return formatStamp(c.Created) }, func(c *Contact, v string) { c.Created = parseStamp(v) }},
+	{"Updated", func(c Contact) string { return formatStamp(c.Updated) }, func(c *Contact, v string) { c.Updated = parseStamp(v) }},
+	{"Modified", func(c Contact) string { return JoinChanges(c.Changes()) }, func(c *Contact, v string) {
+		for _, entry := range SplitEntries(v) {
+			name, stamp, _ := strings.Cut(entry, "=")
+			if at := parseStamp(stamp); !at.IsZero() {
+				c.stampField(strings.TrimSpace(name), at)
+			}
+		}
+	}},
+}
+
+// csvParsers read the columns of csvColumns whose entries can be
+// malformed, in place of their set functions.
+var csvParsers = map[string]func(*Contact, string) error{
+	"Events": func(c *Contact, v string) error {
+		for _, entry := range SplitEntries(v) {
+			event, err := ParseEvent(entry)
+			if err != nil {
+				return err
+			}
+			c.Events = append(c.Events, event)
+		}
+		return nil
+	},
+	"Interactions": func(c *Contact, v string) error {
+		for _, line := range strings.Split(v, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			entry, err := ParseInteraction(line)
+			if err != nil {
+				return err
+			}
+			c.LogInteraction(entry)
+		}
+		return nil
+	},
+}
+
+// fieldColumnPrefix starts the header of the column holding a custom field,
+// as in "Field: slack". WriteCSV adds one such column for every field set on
+// a contact.
+const fieldColumnPrefix = "Field: "
+
+// fieldColumn is the column holding the custom field called name.
+func fieldColumn(name string) csvColumn {
+	return csvColumn{fieldColumnPrefix + name,
+		func(c Contact) string { return c.Field(name) },
+		func(c *Contact, v string) { c.SetField(name, v) }}
+}
+
+// legacyCSVColumns are only read, from files written before contacts could
+// have several emails and phones.
+var legacyCSVColumns = []csvColumn{
+	{"Email", nil, func(c *Contact, v string) {
+		if v != "" {
+			c.Emails = append(c.Emails, Email{Address: v})
+		}
+	}},
+	{"Mobile", nil, func(c *Contact, v string) {
+		if v != "" {
+			c.Phones = append(c.Phones, Phone{Label: LabelMobile, Number: v})
+		}
+	}},
+}
+
+// ReadCSV parses an RFC 4180 address book from r. The first row is the
+// header naming the columns; unknown columns are ignored and missing ones
+// are left empty. Rows without an ID are given one by the store.
+//
+// Files written before the header was introduced hold bare
+// "Name,Email,Mobile" rows. They are still accepted: when the first row is
+// not a header the columns are taken positionally, and since emails and
+// mobiles never contain commas any extra unquoted commas are kept in the
+// name.
+//
+// An event or interaction that cannot be read is reported with its line
+// rather than dropped, as the next save would lose it.
+func ReadCSV(r io.Reader) ([]Contact, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var contacts []Contact
+	var header []*csvColumn
+	first := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if isBlank(record) {
+			continue
+		}
+		if first {
+			first = false
+			if header = headerColumns(record); header != nil {
+				continue
+			}
+		}
+		var c Contact
+		if header == nil {
+			c = legacyRecord(record)
+		} else {
+			for i, col := range header {
+				if col == nil || i >= len(record) {
+					continue
+				}
+				parse, ok := csvParsers[col.name]
+				if !ok {
+					col.set(&c, strings.TrimSpace(record[i]))
+					continue
+				}
+				if err := parse(&c, strings.TrimSpace(record[i])); err != nil {
+					line, _ := reader.FieldPos(i)
+					return nil, fmt.Errorf("line %d: %s: %w", line, col.name, err)
+				}
+			}
+		}
+		c.Normalize()
+		contacts = append(contacts, c)
+	}
+	return contacts, nil
+}
+
+// WriteCSV writes contacts to w as RFC 4180 CSV with a header row.
+func WriteCSV(w io.Writer, contacts []Contact) error {
+	writer := csv.NewWriter(w)
+	columns := append([]csvColumn(nil), csvColumns...)
+	for _, name := range FieldNames(contacts) {
+		columns = append(columns, fieldColumn(name))
+	}
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		row[i] = col.name
+	}
+	if err := writer.Write(row); err != nil {
+		return err
+	}
+	for _, c := range contacts {
+		for i, col := range columns {
+			row[i] = col.get(c)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// headerColumns returns the column found at each position of a header row,
+// nil for unknown ones, or nil when record is not a header row.
+func headerColumns(record []string) []*csvColumn {
+	header := make([]*csvColumn, len(record))
+	hasName, known := false, 0
+	for i, name := range record {
+		name = strings.TrimSpace(name)
+		if field, ok := strings.CutPrefix(name, fieldColumnPrefix); ok && validLabel(strings.ToLower(field)) {
+			col := fieldColumn(strings.ToLower(field))
+			header[i] = &col
+			continue
+		}
+		for _, columns := range [][]csvColumn{csvColumns, legacyCSVColumns} {
+			for j := range columns {
+				if strings.EqualFold(columns[j].name, name) {
+					header[i] = &columns[j]
+					known++
+				}
+			}
+		}
+		hasName = hasName || strings.EqualFold(name, "name")
+	}
+	if !hasName || known < 2 {
+		return nil
+	}
+	return header
+}
+
+// readEntry splits a stored "[*][label:]value" entry, keeping the whole
+// text as the value when it does not parse.
+func readEntry(entry string) (label, value string, primary bool) {
+	label, value, primary, err := parseEntry(entry)
+	if err != nil {
+		return "", strings.TrimSpace(entry), false
+	}
+	return label, value, primary
+}
+
+// legacyRecord reads a headerless "Name,Email,Mobile" row.
+func legacyRecord(record []string) Contact {
+	for len(record) < 3 {
+		record = append(record, "")
+	}
+	n := len(record)
+	var c Contact
+	c.Name = strings.TrimSpace(strings.Join(record[:n-2], ","))
+	legacyCSVColumns[0].set(&c, strings.TrimSpace(record[n-2]))
+	legacyCSVColumns[1].set(&c, strings.TrimSpace(record[n-1]))
+	return c
+}
+
+func isBlank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
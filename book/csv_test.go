@@ -0,0 +1,148 @@
+package book
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	at := time.Date(2026, 9, 30, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		contact Contact
+	}{
+		{"name only", Contact{ID: NewID(), Name: "Jane Doe"}},
+		{"everything", Contact{
+			ID:        NewID(),
+			Name:      "Dr. Jane Q. Doe-Smith Jr.",
+			NameParts: NameParts{Prefix: "Dr.", Given: "Jane", Middle: "Q.", Family: "Doe-Smith", Suffix: "Jr.", Nickname: "JD"},
+			Emails: []Email{
+				{Label: LabelWork, Address: "jane@acme.example", Primary: true},
+				{Label: LabelHome, Address: "jane@example.com"},
+			},
+			Phones: []Phone{
+				{Label: LabelMobile, Number: "+971501234567", Primary: true},
+				{Number: "+14155550199"},
+			},
+			Addresses: []Address{
+				{Label: LabelWork, Street: "Suite 5; Floor 2|East\nTower: B", City: "Dubai", Region: "Dubai", PostalCode: "00000", Country: "AE"},
+				{Street: `C:\Temp, "quoted"`, City: "Paris"},
+			},
+			Organization: "Acme, Inc.",
+			Department:   "R&D",
+			Title:        "Engineer",
+			ManagerID:    NewID(),
+			AssistantID:  NewID(),
+			Tags:         []string{"friends", "Work Team"},
+			Favorite:     true,
+			Events: []Event{
+				{Label: EventBirthday, Date: Date{Year: 1990, Month: 5, Day: 17}},
+				{Label: EventAnniversary, Date: Date{Month: 6, Day: 12}},
+			},
+			Notes:        "Met at the fair.\nLikes tea, not coffee; \"green\" only.",
+			Interactions: []Interaction{{At: at, Kind: InteractionCalled, Text: "about the offer"}},
+			Fields:       map[string]string{"slack": "@jane; @jd", "employee": "42"},
+			Created:      at.Add(-time.Hour),
+			Updated:      at,
+			Modified:     map[string]time.Time{"emails": at, "fields.slack": at.Add(-time.Minute)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteCSV(&buf, []Contact{tt.contact}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadCSV(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.contact) {
+				t.Errorf("read back\n%+v\nwant\n%+v", got, tt.contact)
+			}
+		})
+	}
+}
+
+func TestCSVFieldColumnsCoverEveryContact(t *testing.T) {
+	contacts := []Contact{
+		{ID: NewID(), Name: "Jane Doe", Fields: map[string]string{"slack": "@jane"}},
+		{ID: NewID(), Name: "John Roe", Fields: map[string]string{"employee": "7"}},
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, contacts); err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(buf.String(), "\n")
+	if !strings.HasSuffix(header, ",Field: employee,Field: slack") {
+		t.Errorf("header = %q, want a column for each field", header)
+	}
+	got, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, contacts) {
+		t.Errorf("read back %+v, want %+v", got, contacts)
+	}
+}
+
+func TestReadCSVLegacy(t *testing.T) {
+	input := "Jane Doe,jane@example.com,+971501234567\n" +
+		"Doe, John,john@example.com,+971507654321\n" +
+		"\n" +
+		"Ann Poe,,\n"
+	got, err := ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Contact{
+		{Name: "Jane Doe", Emails: []Email{{Address: "jane@example.com", Primary: true}},
+			Phones: []Phone{{Label: LabelMobile, Number: "+971501234567", Primary: true}}},
+		{Name: "Doe, John", Emails: []Email{{Address: "john@example.com", Primary: true}},
+			Phones: []Phone{{Label: LabelMobile, Number: "+971507654321", Primary: true}}},
+		{Name: "Ann Poe"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadCSV =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReadCSVHeaderInAnyOrder(t *testing.T) {
+	input := "Phones,Unknown,name,Emails\n" +
+		"*mobile:+971501234567,ignored,Jane Doe,work:jane@acme.example;home:jane@example.com\n"
+	got, err := ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Contact{{
+		Name: "Jane Doe",
+		Emails: []Email{
+			{Label: LabelWork, Address: "jane@acme.example", Primary: true},
+			{Label: LabelHome, Address: "jane@example.com"},
+		},
+		Phones: []Phone{{Label: LabelMobile, Number: "+971501234567", Primary: true}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadCSV =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestReadCSVReportsBadEntries(t *testing.T) {
+	tests := []struct {
+		row  string
+		want string
+	}{
+		{`Jane Doe,birthday:1990-02-30,`, `line 3: Events: invalid date "1990-02-30", no such day`},
+		{"Jane Doe,,\"2024-05-01T10:00:00Z met: lunch\nyesterday called\"", `line 3: Interactions: interaction "yesterday called" does not start with a time`},
+	}
+	for _, tt := range tests {
+		input := "Name,Events,Interactions\nJohn Roe,,\n" + tt.row + "\n"
+		_, err := ReadCSV(strings.NewReader(input))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("ReadCSV(%q) error = %v, want %s", tt.row, err, tt.want)
+		}
+	}
+}
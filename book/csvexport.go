@@ -0,0 +1,256 @@
+package book
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// googleHeader starts the header of the CSV files Google Contacts exports
+// and imports; the numbered columns of emails, phones, addresses and
+// events follow.
+var googleHeader = []string{
+	"First Name", "Middle Name", "Last Name",
+	"Phonetic First Name", "Phonetic Middle Name", "Phonetic Last Name",
+	"Name Prefix", "Name Suffix", "Nickname", "File As",
+	"Organization Name", "Organization Title", "Organization Department",
+	"Birthday", "Notes", "Photo", "Labels",
+}
+
+// googleAddressParts are the columns of every numbered Google address.
+var googleAddressParts = []string{
+	"Label", "Formatted", "Street", "City", "PO Box", "Region", "Postal Code", "Country", "Extended Address",
+}
+
+// WriteGoogleCSV writes contacts to w as the CSV file Google Contacts
+// imports. Contacts saved before names were structured have their name
+// split with ParseName. Every email, phone, address and event but the
+// birthday gets numbered columns of its own, labeled as Google labels
+// them ("Mobile", "Work") with a star on the primary one; tags become
+// labels, and favorites are starred.
+func WriteGoogleCSV(w io.Writer, contacts []Contact) error {
+	emails, phones, addresses, events := 1, 1, 1, 1
+	for _, c := range contacts {
+		emails = max(emails, len(c.Emails))
+		phones = max(phones, len(c.Phones))
+		addresses = max(addresses, len(c.Addresses))
+		events = max(events, len(otherEvents(c)))
+	}
+	header := append([]string(nil), googleHeader...)
+	numbered := func(kind string, n int, parts ...string) {
+		for i := 1; i <= n; i++ {
+			for _, part := range parts {
+				header = append(header, fmt.Sprintf("%s %d - %s", kind, i, part))
+			}
+		}
+	}
+	numbered("E-mail", emails, "Label", "Value")
+	numbered("Phone", phones, "Label", "Value")
+	numbered("Address", addresses, googleAddressParts...)
+	numbered("Event", events, "Label", "Value")
+
+	return writeLayout(csv.NewWriter(w), header, contacts, func(c Contact) map[string]string {
+		n := exportName(c)
+		row := map[string]string{
+			"First Name": n.Given, "Middle Name": n.Middle, "Last Name": n.Family,
+			"Name Prefix": n.Prefix, "Name Suffix": n.Suffix, "Nickname": n.Nickname,
+			"Organization Name": c.Organization, "Organization Title": c.Title,
+			"Organization Department": c.Department, "Notes": c.Notes,
+		}
+		if birthday, ok := c.EventDate(EventBirthday); ok {
+			row["Birthday"] = birthday.String()
+		}
+		labels := append([]string(nil), c.Tags...)
+		if c.Favorite {
+			labels = append(labels, "* starred")
+		}
+		row["Labels"] = strings.Join(append(labels, "* myContacts"), " ::: ")
+		for i, e := range c.Emails {
+			row[fmt.Sprintf("E-mail %d - Label", i+1)] = googleLabel(e.Label, e.Primary && len(c.Emails) > 1)
+			row[fmt.Sprintf("E-mail %d - Value", i+1)] = e.Address
+		}
+		for i, p := range c.Phones {
+			row[fmt.Sprintf("Phone %d - Label", i+1)] = googleLabel(p.Label, p.Primary && len(c.Phones) > 1)
+			row[fmt.Sprintf("Phone %d - Value", i+1)] = p.Number
+		}
+		for i, a := range c.Addresses {
+			prefix := fmt.Sprintf("Address %d - ", i+1)
+			row[prefix+"Label"] = googleLabel(a.Label, false)
+			row[prefix+"Formatted"] = strings.Join(a.Lines(), "\n")
+			row[prefix+"Street"] = a.Street
+			row[prefix+"City"] = a.City
+			row[prefix+"Region"] = a.Region
+			row[prefix+"Postal Code"] = a.PostalCode
+			row[prefix+"Country"] = a.Country
+		}
+		for i, e := range otherEvents(c) {
+			row[fmt.Sprintf("Event %d - Label", i+1)] = googleLabel(e.Label, false)
+			row[fmt.Sprintf("Event %d - Value", i+1)] = e.Date.String()
+		}
+		return row
+	})
+}
+
+// googleLabel writes a label the way Google does, capitalized and with a
+// star marking the primary entry.
+func googleLabel(label string, primary bool) string {
+	if label != "" {
+		label = strings.ToUpper(label[:1]) + strings.ReplaceAll(label[1:], "-", " ")
+	}
+	if primary {
+		label = "* " + label
+	}
+	return label
+}
+
+// otherEvents are the events of c but its birthday, which Google has a
+// column of its own for.
+func otherEvents(c Contact) []Event {
+	var events []Event
+	for _, e := range c.Events {
+		if e.Label != EventBirthday {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// outlookHeader is the header of the CSV files Outlook exports and imports.
+var outlookHeader = []string{
+	"First Name", "Middle Name", "Last Name", "Title", "Suffix", "Nickname", "Given Yomi", "Surname Yomi",
+	"E-mail Address", "E-mail 2 Address", "E-mail 3 Address",
+	"Home Phone", "Home Phone 2", "Business Phone", "Business Phone 2", "Mobile Phone", "Car Phone",
+	"Other Phone", "Primary Phone", "Pager", "Business Fax", "Home Fax", "Other Fax",
+	"Company Main Telephone", "Callback", "Radio Phone", "Telex", "TTY/TDD Phone", "IMAddress",
+	"Job Title", "Department", "Company", "Office Location", "Manager's Name", "Assistant's Name",
+	"Assistant's Phone", "Company Yomi",
+	"Business Street", "Business City", "Business State", "Business Postal Code", "Business Country/Region",
+	"Home Street", "Home City", "Home State", "Home Postal Code", "Home Country/Region",
+	"Other Street", "Other City", "Other State", "Other Postal Code", "Other Country/Region",
+	"Personal Web Page", "Spouse", "Schools", "Hobby", "Location", "Web Page",
+	"Birthday", "Anniversary", "Notes", "Categories",
+}
+
+// outlookPhones are the columns a phone with a label may go in, first free
+// column first. Phones with other labels go in Other Phone.
+var outlookPhones = map[string][]string{
+	LabelMobile: {"Mobile Phone"},
+	LabelHome:   {"Home Phone", "Home Phone 2"},
+	LabelWork:   {"Business Phone", "Business Phone 2"},
+	"":          {"Primary Phone"},
+	"main":      {"Company Main Telephone"},
+	"car":       {"Car Phone"},
+	"pager":     {"Pager"},
+	"fax":       {"Business Fax", "Home Fax", "Other Fax"},
+}
+
+// outlookAddresses are the address column groups by label. Addresses with
+// other labels go in the Other group.
+var outlookAddresses = map[string]string{LabelHome: "Home", LabelWork: "Business"}
+
+// WriteOutlookCSV writes contacts to w as the CSV file Outlook imports,
+// starting with a byte order mark so that Outlook reads it as UTF-8.
+// Contacts saved before names were structured have their name split with
+// ParseName. Outlook has fixed columns: a mobile phone goes in Mobile
+// Phone, home and work phones in Home Phone and Business Phone, a phone
+// without a label in Primary Phone and any other one in Other Phone; up
+// to three emails are written, primary first, and one home, work and
+// other address. What does not fit, and dates without a year, which
+// Outlook cannot store, are left out.
+func WriteOutlookCSV(w io.Writer, contacts []Contact) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	return writeLayout(writer, outlookHeader, contacts, func(c Contact) map[string]string {
+		n := exportName(c)
+		row := map[string]string{
+			"First Name": n.Given, "Middle Name": n.Middle, "Last Name": n.Family,
+			"Title": n.Prefix, "Suffix": n.Suffix, "Nickname": n.Nickname,
+			"Job Title": c.Title, "Department": c.Department, "Company": c.Organization,
+			"Notes": c.Notes, "Categories": strings.Join(c.Tags, ";"),
+		}
+		emails := []string{"E-mail Address", "E-mail 2 Address", "E-mail 3 Address"}
+		for _, e := range primaryFirst(c.Emails, func(e Email) bool { return e.Primary }) {
+			if len(emails) > 0 {
+				row[emails[0]] = e.Address
+				emails = emails[1:]
+			}
+		}
+		for _, p := range c.Phones {
+			for _, column := range append(outlookPhones[p.Label], "Other Phone") {
+				if row[column] == "" {
+					row[column] = p.Number
+					break
+				}
+			}
+		}
+		for _, a := range c.Addresses {
+			group, ok := outlookAddresses[a.Label]
+			if !ok {
+				group = "Other"
+			}
+			if row[group+" Street"] != "" || row[group+" City"] != "" || row[group+" Country/Region"] != "" {
+				continue
+			}
+			row[group+" Street"] = a.Street
+			row[group+" City"] = a.City
+			row[group+" State"] = a.Region
+			row[group+" Postal Code"] = a.PostalCode
+			row[group+" Country/Region"] = a.Country
+		}
+		for _, label := range []string{EventBirthday, EventAnniversary} {
+			if d, ok := c.EventDate(label); ok && d.Year != 0 {
+				row[strings.ToUpper(label[:1])+label[1:]] = fmt.Sprintf("%d/%d/%d", d.Month, d.Day, d.Year)
+			}
+		}
+		return row
+	})
+}
+
+// writeLayout writes the header, then a row for every contact with the
+// cells fill returns by column, leaving the others empty.
+func writeLayout(writer *csv.Writer, header []string, contacts []Contact, fill func(Contact) map[string]string) error {
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(header))
+	for _, c := range contacts {
+		cells := fill(c)
+		for i, column := range header {
+			row[i] = cells[column]
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportName is the structured name of c, split from its full name when
+// it was saved before names were structured.
+func exportName(c Contact) NameParts {
+	if !c.NameParts.IsZero() {
+		return c.NameParts
+	}
+	return ParseName(c.Name)
+}
+
+// primaryFirst returns entries with the primary one moved to the front.
+func primaryFirst[T any](entries []T, primary func(T) bool) []T {
+	sorted := make([]T, 0, len(entries))
+	for _, e := range entries {
+		if primary(e) {
+			sorted = append(sorted, e)
+		}
+	}
+	for _, e := range entries {
+		if !primary(e) {
+			sorted = append(sorted, e)
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,516 @@
+package book
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// CSVFields are the parts of a contact a column of an imported CSV file
+// can fill in. Emails, phones, the parts of an address and events take a
+// label, as "phone:mobile"; the parts of an address with the same label
+// make up one address, street lines being joined.
+var CSVFields = []string{
+	"name", "prefix", "given", "middle", "family", "suffix", "nickname",
+	"email", "phone", "street", "city", "region", "postal-code", "country",
+	"organization", "department", "title",
+	"birthday", "anniversary", "event", "notes", "tags",
+}
+
+// ColumnTarget is the part of a contact a column fills in.
+type ColumnTarget struct {
+	// Field is one of CSVFields.
+	Field string
+	// Label is the label of an email, phone, address or event, unless
+	// LabelColumn names the column holding it, as Google writes them.
+	Label       string
+	LabelColumn string
+	// Address groups the columns of one address. The label is used when
+	// it is empty.
+	Address string
+}
+
+// String describes the target, as FIELD[:LABEL] when its label is fixed.
+func (t ColumnTarget) String() string {
+	switch {
+	case t.LabelColumn != "":
+		return fmt.Sprintf("%s, labeled by %q", t.Field, t.LabelColumn)
+	case t.Label != "":
+		return t.Field + ":" + t.Label
+	}
+	return t.Field
+}
+
+// ParseColumnTarget reads a target written as FIELD or FIELD:LABEL.
+func ParseColumnTarget(input string) (ColumnTarget, error) {
+	field, label, _ := strings.Cut(strings.ToLower(strings.TrimSpace(input)), ":")
+	known := false
+	for _, f := range CSVFields {
+		known = known || f == field
+	}
+	if !known {
+		return ColumnTarget{}, fmt.Errorf("unknown field %q, want one of %s", field, strings.Join(CSVFields, ", "))
+	}
+	if label != "" && !validLabel(label) {
+		return ColumnTarget{}, fmt.Errorf("invalid label %q, use letters, digits and dashes", label)
+	}
+	return ColumnTarget{Field: field, Label: label}, nil
+}
+
+// CSVMapping tells which part of a contact each column of a CSV file fills
+// in, by header. Headers are compared ignoring case, and columns it does
+// not name are ignored.
+type CSVMapping struct {
+	// Layout names the mapping: "google", "outlook" or the file it was
+	// read from.
+	Layout  string
+	Columns map[string]ColumnTarget
+}
+
+// Target returns what the column with the given header fills in.
+func (m CSVMapping) Target(header string) (ColumnTarget, bool) {
+	header = strings.TrimSpace(header)
+	if t, ok := m.Columns[header]; ok {
+		return t, true
+	}
+	for name, t := range m.Columns {
+		if strings.EqualFold(name, header) {
+			return t, true
+		}
+	}
+	return ColumnTarget{}, false
+}
+
+// LoadCSVMapping reads the column mapping file at path.
+func LoadCSVMapping(path string) (CSVMapping, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return CSVMapping{}, err
+	}
+	defer file.Close()
+	m, err := ReadCSVMapping(file)
+	if err != nil {
+		return CSVMapping{}, fmt.Errorf("%s: %w", path, err)
+	}
+	m.Layout = path
+	return m, nil
+}
+
+// ReadCSVMapping parses a column mapping document such as
+//
+//	{"columns": {
+//	  "Full Name": "name",
+//	  "Work Email": "email:work",
+//	  "Cell": "phone:mobile",
+//	  "Street": "street:home",
+//	  "Town": "city:home"
+//	}}
+//
+// giving, for each header, the field the column fills in as FIELD or
+// FIELD:LABEL; see CSVFields.
+func ReadCSVMapping(r io.Reader) (CSVMapping, error) {
+	var doc struct {
+		Columns map[string]string `json:"columns"`
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return CSVMapping{}, err
+	}
+	if len(doc.Columns) == 0 {
+		return CSVMapping{}, errors.New("no columns are mapped")
+	}
+	m := CSVMapping{Layout: "custom", Columns: map[string]ColumnTarget{}}
+	for header, input := range doc.Columns {
+		t, err := ParseColumnTarget(input)
+		if err != nil {
+			return CSVMapping{}, fmt.Errorf("column %q: %w", header, err)
+		}
+		m.Columns[strings.TrimSpace(header)] = t
+	}
+	return m, nil
+}
+
+// outlookColumns are the columns of the CSV files Outlook exports, in the
+// form of a mapping file. Outlook calls the name prefix "Title".
+var outlookColumns = map[string]string{
+	"First Name": "given", "Middle Name": "middle", "Last Name": "family",
+	"Title": "prefix", "Suffix": "suffix", "Nickname": "nickname",
+	"E-mail Address": "email", "E-mail 2 Address": "email", "E-mail 3 Address": "email",
+	"Mobile Phone": "phone:mobile", "Primary Phone": "phone",
+	"Home Phone": "phone:home", "Home Phone 2": "phone:home",
+	"Business Phone": "phone:work", "Business Phone 2": "phone:work",
+	"Other Phone": "phone:other", "Company Main Telephone": "phone:main",
+	"Car Phone": "phone:car", "Pager": "phone:pager",
+	"Business Fax": "phone:fax", "Home Fax": "phone:fax", "Other Fax": "phone:fax",
+	"Company": "organization", "Department": "department", "Job Title": "title",
+	"Business Street": "street:work", "Business Street 2": "street:work", "Business Street 3": "street:work",
+	"Business City": "city:work", "Business State": "region:work",
+	"Business Postal Code": "postal-code:work", "Business Country/Region": "country:work",
+	"Home Street": "street:home", "Home Street 2": "street:home", "Home Street 3": "street:home",
+	"Home City": "city:home", "Home State": "region:home",
+	"Home Postal Code": "postal-code:home", "Home Country/Region": "country:home",
+	"Other Street": "street:other", "Other Street 2": "street:other", "Other Street 3": "street:other",
+	"Other City": "city:other", "Other State": "region:other",
+	"Other Postal Code": "postal-code:other", "Other Country/Region": "country:other",
+	"Birthday": "birthday", "Anniversary": "anniversary", "Notes": "notes", "Categories": "tags",
+}
+
+// googleColumns are the columns of the CSV files Google Contacts exports
+// that are not numbered, in both its current and its older layout.
+var googleColumns = map[string]string{
+	"Name": "name", "First Name": "given", "Given Name": "given",
+	"Middle Name": "middle", "Additional Name": "middle",
+	"Last Name": "family", "Family Name": "family",
+	"Name Prefix": "prefix", "Name Suffix": "suffix", "Nickname": "nickname",
+	"Organization Name": "organization", "Organization Title": "title", "Organization Department": "department",
+	"Birthday": "birthday", "Notes": "notes", "Labels": "tags", "Group Membership": "tags",
+}
+
+// googleNumbered matches the numbered columns of Google, such as
+// "E-mail 1 - Value" or "Address 2 - City".
+var googleNumbered = regexp.MustCompile(`^(E-mail|Phone|Address|Organization|Event) (\d+) - (.+)$`)
+
+// googleParts maps the last part of numbered Google headers onto fields.
+var googleParts = map[string]map[string]string{
+	"E-mail": {"Value": "email"},
+	"Phone":  {"Value": "phone"},
+	"Address": {"Street": "street", "PO Box": "street", "Extended Address": "street", "City": "city",
+		"Region": "region", "Postal Code": "postal-code", "Country": "country"},
+	"Organization": {"Name": "organization", "Title": "title", "Department": "department"},
+	"Event":        {"Value": "event"},
+}
+
+// DetectCSVMapping recognizes the layouts of the CSV files Google Contacts
+// and Outlook export from their header.
+func DetectCSVMapping(header []string) (CSVMapping, bool) {
+	has := map[string]bool{}
+	numbered := false
+	for _, h := range header {
+		h = strings.TrimSpace(h)
+		has[h] = true
+		numbered = numbered || googleNumbered.MatchString(h)
+	}
+	switch {
+	case numbered:
+		return googleMapping(header, has), true
+	case has["First Name"] && has["Last Name"] && (has["E-mail Address"] || has["Mobile Phone"]):
+		m := CSVMapping{Layout: "outlook", Columns: map[string]ColumnTarget{}}
+		for h, input := range outlookColumns {
+			m.Columns[h], _ = ParseColumnTarget(input)
+		}
+		return m, true
+	}
+	return CSVMapping{}, false
+}
+
+// googleMapping maps the columns of a Google Contacts file, whose numbered
+// columns take their label from the "Label" (or, in the older layout,
+// "Type") column with the same number.
+func googleMapping(header []string, has map[string]bool) CSVMapping {
+	m := CSVMapping{Layout: "google", Columns: map[string]ColumnTarget{}}
+	for _, h := range header {
+		h = strings.TrimSpace(h)
+		if input, ok := googleColumns[h]; ok {
+			m.Columns[h], _ = ParseColumnTarget(input)
+			continue
+		}
+		match := googleNumbered.FindStringSubmatch(h)
+		if match == nil {
+			continue
+		}
+		kind, number, part := match[1], match[2], match[3]
+		field, ok := googleParts[kind][part]
+		if !ok || kind == "Organization" && number != "1" {
+			continue
+		}
+		t := ColumnTarget{Field: field}
+		if kind != "Organization" {
+			t.LabelColumn = fmt.Sprintf("%s %s - Label", kind, number)
+			if !has[t.LabelColumn] {
+				t.LabelColumn = fmt.Sprintf("%s %s - Type", kind, number)
+			}
+		}
+		if kind == "Address" {
+			t.Address = "address " + number
+		}
+		m.Columns[h] = t
+	}
+	return m
+}
+
+// MappedCSV is what ReadMappedCSV read: the header of the file, the
+// mapping applied, the contacts and the rows rejected.
+type MappedCSV struct {
+	Header   []string
+	Mapping  CSVMapping
+	Contacts []Contact
+	Rejected []Rejected
+}
+
+// ReadMappedCSV reads a CSV file with a header row, such as those Google
+// Contacts and Outlook export, and maps every row onto a contact with
+// mapping, or with the layout DetectCSVMapping recognizes when mapping has
+// no columns. Emails and phones are validated as NewContact does, with
+// numbers lacking a country code read as numbers of region. A row with an
+// invalid value or without a name is rejected with the reason, naming the
+// column at fault. Lists, such as the emails Google writes in one cell,
+// are separated by ":::", and tags by semicolons or commas too.
+func ReadMappedCSV(r io.Reader, mapping CSVMapping, region string) (MappedCSV, error) {
+	// Outlook starts its files with a byte order mark
+	br := bufio.NewReader(r)
+	if bom, err := br.Peek(3); err == nil && string(bom) == "\ufeff" {
+		br.Discard(3)
+	}
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return MappedCSV{}, errors.New("the file is empty")
+	}
+	if err != nil {
+		return MappedCSV{}, err
+	}
+	if len(mapping.Columns) == 0 {
+		var ok bool
+		if mapping, ok = DetectCSVMapping(header); !ok {
+			return MappedCSV{}, errors.New("unknown CSV layout, give a column mapping file")
+		}
+	}
+	result := MappedCSV{Header: header, Mapping: mapping}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return MappedCSV{}, err
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		contact, err := mapping.contact(header, row, region)
+		if err != nil {
+			result.Rejected = append(result.Rejected, Rejected{Line: line, Name: contact.Name, Reason: err})
+			continue
+		}
+		result.Contacts = append(result.Contacts, contact)
+	}
+	return result, nil
+}
+
+// contact maps one row. The contact carries the name of the row even when
+// an error is returned.
+func (m CSVMapping) contact(header, row []string, region string) (Contact, error) {
+	var c Contact
+	var name string
+	var parts NameParts
+	var notes []string
+	// the parts of every address, by the key grouping them, in the order
+	// they first appear
+	var keys []string
+	streets := map[string][]string{}
+	found := map[string]*Address{}
+	cell := func(h string) string {
+		for i, other := range header {
+			if i < len(row) && strings.EqualFold(strings.TrimSpace(other), h) {
+				return row[i]
+			}
+		}
+		return ""
+	}
+	// the errors of the cells are reported once the name is known
+	var first error
+	fail := func(column string, err error) {
+		if first == nil {
+			first = fmt.Errorf("column %q: %w", column, err)
+		}
+	}
+	for i, value := range row {
+		value = strings.TrimSpace(value)
+		if i >= len(header) || value == "" {
+			continue
+		}
+		column := strings.TrimSpace(header[i])
+		t, ok := m.Target(column)
+		if !ok {
+			continue
+		}
+		label, primary := t.Label, false
+		if t.LabelColumn != "" {
+			label, primary = csvLabel(cell(t.LabelColumn))
+		}
+		switch t.Field {
+		case "name":
+			name = value
+		case "prefix":
+			parts.Prefix = value
+		case "given":
+			parts.Given = value
+		case "middle":
+			parts.Middle = value
+		case "family":
+			parts.Family = value
+		case "suffix":
+			parts.Suffix = value
+		case "nickname":
+			parts.Nickname = value
+		case "email":
+			for _, v := range splitCSVList(value, ":::") {
+				if !IsValidEmail(v) {
+					fail(column, fmt.Errorf("%w: %q", ErrInvalidEmail, v))
+					continue
+				}
+				c.Emails = append(c.Emails, Email{Label: label, Address: v, Primary: primary})
+			}
+		case "phone":
+			for _, v := range splitCSVList(value, ":::") {
+				number, err := ParsePhone(v, region)
+				if err != nil {
+					fail(column, fmt.Errorf("%q: %w", v, err))
+					continue
+				}
+				c.Phones = append(c.Phones, Phone{Label: label, Number: number, Primary: primary})
+			}
+		case "street", "city", "region", "postal-code", "country":
+			key := t.Address
+			if key == "" {
+				key = label
+			}
+			a, ok := found[key]
+			if !ok {
+				a = &Address{}
+				found[key] = a
+				keys = append(keys, key)
+			}
+			if label != "" {
+				a.Label = label
+			}
+			switch t.Field {
+			case "street":
+				streets[key] = append(streets[key], value)
+			case "city":
+				a.City = value
+			case "region":
+				a.Region = value
+			case "postal-code":
+				a.PostalCode = value
+			case "country":
+				a.Country = value
+			}
+		case "organization":
+			c.Organization = value
+		case "department":
+			c.Department = value
+		case "title":
+			c.Title = value
+		case "birthday", "anniversary", "event":
+			date, ok, err := parseCSVDate(value)
+			if err != nil {
+				fail(column, err)
+				continue
+			}
+			if t.Field != "event" {
+				label = t.Field
+			} else if label == "" {
+				label = LabelOther
+			}
+			if ok {
+				c.SetEvent(label, date)
+			}
+		case "notes":
+			notes = append(notes, value)
+		case "tags":
+			for _, v := range splitCSVList(value, ":::", ";", ",") {
+				// Google marks its own groups with a star
+				if system, ok := strings.CutPrefix(v, "* "); ok {
+					c.Favorite = c.Favorite || strings.EqualFold(system, "starred")
+					continue
+				}
+				if tag, err := NormalizeTag(v); err == nil {
+					c.AddTag(tag)
+				}
+			}
+		}
+	}
+	for _, key := range keys {
+		parts := found[key]
+		a, err := NewAddress(parts.Label, strings.Join(streets[key], "\n"), parts.City, parts.Region, parts.PostalCode, parts.Country)
+		if err == nil {
+			c.Addresses = append(c.Addresses, a)
+		}
+	}
+	c.Notes = NormalizeNotes(strings.Join(notes, "\n"))
+	nickname := parts.Nickname
+	parts.Nickname = ""
+	if parts != (NameParts{}) {
+		c.SetNameParts(parts)
+	} else {
+		c.SetName(name)
+	}
+	if nickname != "" && c.Name != "" {
+		c.NameParts.Nickname = nickname
+	}
+	c.Normalize()
+	if first == nil && c.Name == "" {
+		first = ErrEmptyName
+	}
+	return c, first
+}
+
+// csvLabel reads a label written in a column of its own, such as Google's
+// "* Mobile", where the star marks the primary entry. Labels that are not
+// a single word are dropped.
+func csvLabel(value string) (label string, primary bool) {
+	value = strings.TrimSpace(value)
+	if rest, ok := strings.CutPrefix(value, "*"); ok {
+		primary = true
+		value = rest
+	}
+	label = strings.ToLower(strings.Join(strings.Fields(value), "-"))
+	if l, ok := vcardLabels[label]; ok {
+		label = l
+	}
+	if !validLabel(label) {
+		label = ""
+	}
+	return label, primary
+}
+
+// splitCSVList splits a cell holding several values on any of seps.
+func splitCSVList(value string, seps ...string) []string {
+	for _, sep := range seps[1:] {
+		value = strings.ReplaceAll(value, sep, seps[0])
+	}
+	var values []string
+	for _, v := range strings.Split(value, seps[0]) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseCSVDate reads a date as ParseDate does or as Outlook writes it,
+// 5/17/1990. Outlook's 0/0/00 is no date, reported with ok false.
+func parseCSVDate(value string) (d Date, ok bool, err error) {
+	if value == "0/0/00" {
+		return Date{}, false, nil
+	}
+	if d, err = ParseDate(value); err == nil {
+		return d, true, nil
+	}
+	if t, terr := time.Parse("1/2/2006", value); terr == nil {
+		return Date{Year: t.Year(), Month: t.Month(), Day: t.Day()}, true, nil
+	}
+	return Date{}, false, err
+}
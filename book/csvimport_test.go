@@ -0,0 +1,196 @@
+package book
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadMappedCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		layout string
+		want   []Contact
+	}{
+		{
+			name: "google",
+			input: "First Name,Last Name,Nickname,Organization Name,Organization Title,Birthday,Notes,Labels," +
+				"E-mail 1 - Label,E-mail 1 - Value,Phone 1 - Label,Phone 1 - Value,Phone 2 - Label,Phone 2 - Value," +
+				"Address 1 - Label,Address 1 - Street,Address 1 - City,Address 1 - Postal Code,Address 1 - Country,Event 1 - Label,Event 1 - Value\n" +
+				"Jane,Doe,JD,Acme,Engineer,1990-05-17,Met at the fair,Friends ::: * starred ::: * myContacts," +
+				"* Work,jane@acme.example ::: jane@example.com,* Mobile,050 123 4567,Home,+44 20 7946 0018," +
+				"Home,12 Palm Street,Dubai,00000,AE,Anniversary,2015-06-01\n",
+			layout: "google",
+			want: []Contact{{
+				Name:      "Jane Doe",
+				NameParts: NameParts{Given: "Jane", Family: "Doe", Nickname: "JD"},
+				Emails: []Email{
+					{Label: LabelWork, Address: "jane@acme.example", Primary: true},
+					{Label: LabelWork, Address: "jane@example.com"},
+				},
+				Phones: []Phone{
+					{Label: LabelMobile, Number: "+971501234567", Primary: true},
+					{Label: LabelHome, Number: "+442079460018"},
+				},
+				Addresses:    []Address{{Label: LabelHome, Street: "12 Palm Street", City: "Dubai", PostalCode: "00000", Country: "AE"}},
+				Organization: "Acme",
+				Title:        "Engineer",
+				Tags:         []string{"Friends"},
+				Favorite:     true,
+				Events: []Event{
+					{Label: EventBirthday, Date: Date{Year: 1990, Month: 5, Day: 17}},
+					{Label: EventAnniversary, Date: Date{Year: 2015, Month: 6, Day: 1}},
+				},
+				Notes: "Met at the fair",
+			}},
+		},
+		{
+			name: "google older layout",
+			input: "Name,Given Name,Family Name,Group Membership,E-mail 1 - Type,E-mail 1 - Value\n" +
+				"John Roe,John,Roe,* My Contacts ::: Work,Home,john@example.com\n",
+			layout: "google",
+			want: []Contact{{
+				Name:      "John Roe",
+				NameParts: NameParts{Given: "John", Family: "Roe"},
+				Emails:    []Email{{Label: LabelHome, Address: "john@example.com", Primary: true}},
+				Tags:      []string{"Work"},
+			}},
+		},
+		{
+			name: "outlook",
+			input: "\ufeffFirst Name,Middle Name,Last Name,Title,E-mail Address,E-mail 2 Address,Mobile Phone,Business Phone," +
+				"Company,Job Title,Business Street,Business City,Business Country/Region,Birthday,Anniversary,Categories\r\n" +
+				"Ann,Marie,Poe,Dr.,ann@example.com,ann@work.example,+971 50 765 4321,04 123 4567," +
+				"Acme,Director,1 Main Street,Dubai,AE,5/17/1980,0/0/00,Board;Clients\r\n",
+			layout: "outlook",
+			want: []Contact{{
+				Name:      "Dr. Ann Marie Poe",
+				NameParts: NameParts{Prefix: "Dr.", Given: "Ann", Middle: "Marie", Family: "Poe"},
+				Emails: []Email{
+					{Address: "ann@example.com", Primary: true},
+					{Address: "ann@work.example"},
+				},
+				Phones: []Phone{
+					{Label: LabelMobile, Number: "+971507654321", Primary: true},
+					{Label: LabelWork, Number: "+97141234567"},
+				},
+				Addresses:    []Address{{Label: LabelWork, Street: "1 Main Street", City: "Dubai", Country: "AE"}},
+				Organization: "Acme",
+				Title:        "Director",
+				Tags:         []string{"Board", "Clients"},
+				Events:       []Event{{Label: EventBirthday, Date: Date{Year: 1980, Month: 5, Day: 17}}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMappedCSV(strings.NewReader(tt.input), CSVMapping{}, "AE")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Mapping.Layout != tt.layout {
+				t.Errorf("layout = %q, want %q", got.Mapping.Layout, tt.layout)
+			}
+			if len(got.Rejected) > 0 {
+				t.Errorf("rejected %v", got.Rejected)
+			}
+			if !reflect.DeepEqual(got.Contacts, tt.want) {
+				t.Errorf("contacts =\n%+v\nwant\n%+v", got.Contacts, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMappedCSVRejects(t *testing.T) {
+	input := "First Name,Last Name,E-mail Address,Mobile Phone\n" +
+		"Jane,Doe,jane@example.com,050 123 4567\n" +
+		"John,Roe,not an email,\n" +
+		",,ann@example.com,\n" +
+		"Bob,Loe,,12\n"
+	got, err := ReadMappedCSV(strings.NewReader(input), CSVMapping{}, "AE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Contacts) != 1 || got.Contacts[0].Name != "Jane Doe" {
+		t.Errorf("contacts = %+v, want Jane Doe only", got.Contacts)
+	}
+	want := []string{
+		`line 3 (John Roe): column "E-mail Address": invalid email format: "not an email"`,
+		`line 4: name is required`,
+		`line 5 (Bob Loe): column "Mobile Phone": "12": invalid phone number: AE numbers have 8 or 9 digits after the leading 0`,
+	}
+	var reasons []string
+	for _, r := range got.Rejected {
+		reasons = append(reasons, r.String())
+	}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("rejected =\n%s\nwant\n%s", strings.Join(reasons, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadMappedCSVUnknownLayout(t *testing.T) {
+	if _, err := ReadMappedCSV(strings.NewReader("Full Name,Cell\nJane Doe,050 123 4567\n"), CSVMapping{}, "AE"); err == nil {
+		t.Error("ReadMappedCSV of an unknown layout succeeded without a mapping")
+	}
+	mapping, err := ReadCSVMapping(strings.NewReader(`{"columns": {"Full Name": "name", "Cell": "phone:mobile"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadMappedCSV(strings.NewReader("Full Name,Cell\nJane Doe,050 123 4567\n"), mapping, "AE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Contacts) != 1 || got.Contacts[0].Name != "Jane Doe" || got.Contacts[0].PrimaryPhone() != "+971501234567" {
+		t.Errorf("contacts = %+v", got.Contacts)
+	}
+}
+
+func TestExportedCSVReadsBack(t *testing.T) {
+	jane := Contact{
+		Name:      "Jane Doe",
+		NameParts: NameParts{Given: "Jane", Family: "Doe"},
+		Emails: []Email{
+			{Label: LabelWork, Address: "jane@acme.example", Primary: true},
+			{Label: LabelHome, Address: "jane@example.com"},
+		},
+		Phones:       []Phone{{Label: LabelMobile, Number: "+971501234567", Primary: true}},
+		Addresses:    []Address{{Label: LabelHome, Street: "12 Palm Street", City: "Dubai", Country: "AE"}},
+		Organization: "Acme",
+		Title:        "Engineer",
+		Tags:         []string{"friends"},
+		Events:       []Event{{Label: EventBirthday, Date: Date{Year: 1990, Month: 5, Day: 17}}},
+		Notes:        "Met at the fair",
+	}
+	writers := map[string]func(*bytes.Buffer) error{
+		"google":  func(b *bytes.Buffer) error { return WriteGoogleCSV(b, []Contact{jane}) },
+		"outlook": func(b *bytes.Buffer) error { return WriteOutlookCSV(b, []Contact{jane}) },
+	}
+	for layout, write := range writers {
+		t.Run(layout, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadMappedCSV(&buf, CSVMapping{}, "AE")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Mapping.Layout != layout || len(got.Rejected) > 0 || len(got.Contacts) != 1 {
+				t.Fatalf("ReadMappedCSV = %s, %v, %d contacts", got.Mapping.Layout, got.Rejected, len(got.Contacts))
+			}
+			c := got.Contacts[0]
+			if c.Name != jane.Name || c.Organization != jane.Organization || c.Title != jane.Title || c.Notes != jane.Notes {
+				t.Errorf("read back %+v, want %+v", c, jane)
+			}
+			if !reflect.DeepEqual(c.Phones, jane.Phones) || !reflect.DeepEqual(c.Addresses, jane.Addresses) ||
+				!reflect.DeepEqual(c.Tags, jane.Tags) || !reflect.DeepEqual(c.Events, jane.Events) {
+				t.Errorf("read back %+v, want %+v", c, jane)
+			}
+			if len(c.Emails) != 2 || c.PrimaryEmail() != "jane@acme.example" {
+				t.Errorf("emails read back = %+v, want %+v", c.Emails, jane.Emails)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package book
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Labels of the events every calendar knows. Any other label names a
+// custom event, such as "graduation".
+const (
+	EventBirthday    = "birthday"
+	EventAnniversary = "anniversary"
+)
+
+// ErrInvalidDate is returned for a date that does not exist or cannot be
+// read.
+var ErrInvalidDate = errors.New("invalid date")
+
+// Date is a day of the year, with the year left at 0 when it is unknown.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// ParseDate reads a date written as "YYYY-MM-DD", or as "--MM-DD" or
+// "MM-DD" when the year is unknown. February 29 is accepted without a year
+// and in leap years.
+func ParseDate(input string) (Date, error) {
+	s := strings.TrimSpace(input)
+	var fields []string
+	if rest, ok := strings.CutPrefix(s, "--"); ok {
+		fields = append([]string{""}, strings.Split(rest, "-")...)
+	} else {
+		fields = strings.Split(s, "-")
+		if len(fields) == 2 {
+			fields = append([]string{""}, fields...)
+		}
+	}
+	malformed := fmt.Errorf("%w %q, want YYYY-MM-DD or --MM-DD", ErrInvalidDate, input)
+	if len(fields) != 3 {
+		return Date{}, malformed
+	}
+	var d Date
+	var month int
+	var err error
+	if fields[0] != "" {
+		if d.Year, err = strconv.Atoi(fields[0]); err != nil || len(fields[0]) != 4 || d.Year < 1 {
+			return Date{}, malformed
+		}
+	}
+	if month, err = strconv.Atoi(fields[1]); err != nil {
+		return Date{}, malformed
+	}
+	if d.Day, err = strconv.Atoi(fields[2]); err != nil {
+		return Date{}, malformed
+	}
+	d.Month = time.Month(month)
+	if !d.valid() {
+		return Date{}, fmt.Errorf("%w %q, no such day", ErrInvalidDate, input)
+	}
+	return d, nil
+}
+
+// valid reports whether the day exists, in a leap year when the year is
+// unknown.
+func (d Date) valid() bool {
+	year := d.Year
+	if year == 0 {
+		year = 2000
+	}
+	t := time.Date(year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+	return d.Month >= time.January && d.Month <= time.December && t.Month() == d.Month && t.Day() == d.Day
+}
+
+// IsZero reports whether the date is not set.
+func (d Date) IsZero() bool {
+	return d == Date{}
+}
+
+// String renders the date as ParseDate reads it, with "--" standing for an
+// unknown year as in vCard.
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	if d.Year == 0 {
+		return fmt.Sprintf("--%02d-%02d", d.Month, d.Day)
+	}
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// MarshalText stores the date as String renders it.
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText reads the date with ParseDate.
+func (d *Date) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// In returns the day, at midnight UTC, the date falls on in year.
+// February 29 falls on February 28 in other years.
+func (d Date) In(year int) time.Time {
+	day := d.Day
+	if d.Month == time.February && day == 29 && !isLeap(year) {
+		day = 28
+	}
+	return time.Date(year, d.Month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// Event is a date a contact celebrates every year.
+type Event struct {
+	Label string `json:"label"`
+	Date  Date   `json:"date"`
+}
+
+// ParseEvent reads an event written as "label:date", for example
+// "birthday:1990-05-17" or "anniversary:--06-12", with the date in the form
+// accepted by ParseDate.
+func ParseEvent(input string) (Event, error) {
+	label, value, ok := strings.Cut(strings.TrimSpace(input), ":")
+	label = strings.ToLower(strings.TrimSpace(label))
+	if !ok {
+		return Event{}, fmt.Errorf("event %q needs a label, as in birthday:%s", input, strings.TrimSpace(input))
+	}
+	if !validLabel(label) {
+		return Event{}, fmt.Errorf("invalid label %q, use letters, digits and dashes", label)
+	}
+	date, err := ParseDate(value)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Label: label, Date: date}, nil
+}
+
+// String renders the event as ParseEvent reads it.
+func (e Event) String() string {
+	return e.Label + ":" + e.Date.String()
+}
+
+// JoinEvents renders events as one semicolon separated list.
+func JoinEvents(events []Event) string {
+	parts := make([]string, len(events))
+	for i, e := range events {
+		parts[i] = e.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// EventDate returns the date of the event with the given label.
+func (c Contact) EventDate(label string) (Date, bool) {
+	if i := indexEvent(c.Events, label); i >= 0 {
+		return c.Events[i].Date, true
+	}
+	return Date{}, false
+}
+
+// SetEvent sets the date of the event with the given label, adding the
+// event when the contact has none with that label.
+func (c *Contact) SetEvent(label string, date Date) {
+	if i := indexEvent(c.Events, label); i >= 0 {
+		c.Events[i].Date = date
+		return
+	}
+	c.Events = append(c.Events, Event{Label: strings.ToLower(label), Date: date})
+}
+
+// RemoveEvent drops the event with the given label and reports whether the
+// contact had it.
+func (c *Contact) RemoveEvent(label string) bool {
+	i := indexEvent(c.Events, label)
+	if i < 0 {
+		return false
+	}
+	c.Events = slices.Concat(c.Events[:i], c.Events[i+1:])
+	return true
+}
+
+func indexEvent(events []Event, label string) int {
+	for i, e := range events {
+		if strings.EqualFold(e.Label, strings.TrimSpace(label)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Occurrence is the next time an event of a contact comes round.
+type Occurrence struct {
+	Contact Contact
+	Event   Event
+	// Date is the day of the occurrence and Days the number of days until
+	// it, 0 being today.
+	Date time.Time
+	Days int
+	// Years is the age turned or the number of years celebrated, 0 when the
+	// year of the event is unknown.
+	Years int
+}
+
+// Upcoming returns the events of contacts that come round in the days
+// following from, today included, sorted by date and then by name.
+func Upcoming(contacts []Contact, from time.Time, days int) []Occurrence {
+	today := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	var found []Occurrence
+	for _, c := range contacts {
+		for _, e := range c.Events {
+			if e.Date.IsZero() {
+				continue
+			}
+			year := today.Year()
+			next := e.Date.In(year)
+			if next.Before(today) {
+				year++
+				next = e.Date.In(year)
+			}
+			in := int(next.Sub(today).Hours() / 24)
+			if in > days {
+				continue
+			}
+			o := Occurrence{Contact: c, Event: e, Date: next, Days: in}
+			if e.Date.Year > 0 && year > e.Date.Year {
+				o.Years = year - e.Date.Year
+			}
+			found = append(found, o)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Days != found[j].Days {
+			return found[i].Days < found[j].Days
+		}
+		return strings.ToLower(found[i].Contact.DisplayName(GivenFirst)) < strings.ToLower(found[j].Contact.DisplayName(GivenFirst))
+	})
+	return found
+}
@@ -0,0 +1,232 @@
+package book
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultSchemaPath is the schema file used when none is given.
+const DefaultSchemaPath = "contacts.fields.json"
+
+// ErrInvalidField is returned for a custom field value that does not fit
+// the type of the field, or for a field the schema does not declare.
+var ErrInvalidField = errors.New("invalid custom field")
+
+// FieldType is the kind of value a custom field holds.
+type FieldType string
+
+// Types of custom fields.
+const (
+	FieldText    FieldType = "text"
+	FieldNumber  FieldType = "number"
+	FieldDate    FieldType = "date"
+	FieldURL     FieldType = "url"
+	FieldBoolean FieldType = "boolean"
+	FieldEnum    FieldType = "enum"
+)
+
+// FieldDef declares a custom field. Name is the key the value is stored
+// under; Title, when set, is shown instead of it. An enum field takes one
+// of Values.
+type FieldDef struct {
+	Name   string    `json:"name"`
+	Title  string    `json:"title,omitempty"`
+	Type   FieldType `json:"type"`
+	Values []string  `json:"values,omitempty"`
+}
+
+// Schema is the list of custom fields users have declared, in the order
+// they are shown.
+type Schema struct {
+	Fields []FieldDef `json:"fields"`
+}
+
+// LoadSchema reads the schema file at path. A missing file declares no
+// fields.
+func LoadSchema(path string) (Schema, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Schema{}, nil
+	}
+	if err != nil {
+		return Schema{}, err
+	}
+	defer file.Close()
+	schema, err := ReadSchema(file)
+	if err != nil {
+		return Schema{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// ReadSchema parses a schema document such as
+//
+//	{"fields": [
+//	  {"name": "employee", "title": "Employee #", "type": "number"},
+//	  {"name": "slack", "type": "text"},
+//	  {"name": "team", "type": "enum", "values": ["sales", "support"]}
+//	]}
+//
+// and checks every declaration.
+func ReadSchema(r io.Reader) (Schema, error) {
+	var schema Schema
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&schema); err != nil {
+		return Schema{}, err
+	}
+	seen := map[string]bool{}
+	for i, f := range schema.Fields {
+		f.Name = strings.ToLower(strings.TrimSpace(f.Name))
+		if !validLabel(f.Name) {
+			return Schema{}, fmt.Errorf("field %d: invalid name %q, use letters, digits and dashes", i+1, f.Name)
+		}
+		if seen[f.Name] {
+			return Schema{}, fmt.Errorf("field %s is declared twice", f.Name)
+		}
+		seen[f.Name] = true
+		f.Type = FieldType(strings.ToLower(string(f.Type)))
+		switch f.Type {
+		case FieldText, FieldNumber, FieldDate, FieldURL, FieldBoolean:
+			if len(f.Values) > 0 {
+				return Schema{}, fmt.Errorf("field %s: only enum fields have values", f.Name)
+			}
+		case FieldEnum:
+			if len(f.Values) == 0 {
+				return Schema{}, fmt.Errorf("field %s: an enum needs values", f.Name)
+			}
+		default:
+			return Schema{}, fmt.Errorf("field %s: unknown type %q (want text, number, date, url, boolean or enum)", f.Name, f.Type)
+		}
+		schema.Fields[i] = f
+	}
+	return schema, nil
+}
+
+// Field returns the declaration of the field called name, ignoring case.
+func (s Schema) Field(name string) (FieldDef, bool) {
+	for _, f := range s.Fields {
+		if strings.EqualFold(f.Name, strings.TrimSpace(name)) {
+			return f, true
+		}
+	}
+	return FieldDef{}, false
+}
+
+// Set validates value against the declaration of the field called name and
+// stores it on the contact. An empty value removes the field.
+func (s Schema) Set(c *Contact, name, value string) error {
+	f, ok := s.Field(name)
+	if !ok {
+		return fmt.Errorf("%w: no field %q is declared", ErrInvalidField, name)
+	}
+	value, err := f.Normalize(value)
+	if err != nil {
+		return err
+	}
+	c.SetField(f.Name, value)
+	return nil
+}
+
+// Heading is the title of the field, or its name.
+func (f FieldDef) Heading() string {
+	if f.Title != "" {
+		return f.Title
+	}
+	return f.Name
+}
+
+// Normalize checks that value fits the type of the field and returns it in
+// its canonical form: numbers as typed without spaces, dates as
+// Date.String renders them, booleans as "true" or "false" and enum values
+// as declared. An empty value is always accepted.
+func (f FieldDef) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	invalid := func(want string) error {
+		return fmt.Errorf("%w: %s wants %s, not %q", ErrInvalidField, f.Name, want, value)
+	}
+	switch f.Type {
+	case FieldNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", invalid("a number")
+		}
+	case FieldDate:
+		d, err := ParseDate(value)
+		if err != nil {
+			return "", invalid("a date as YYYY-MM-DD")
+		}
+		value = d.String()
+	case FieldURL:
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" && u.Opaque == "" {
+			return "", invalid("a URL such as https://example.com")
+		}
+	case FieldBoolean:
+		switch strings.ToLower(value) {
+		case "true", "yes", "y", "1":
+			value = "true"
+		case "false", "no", "n", "0":
+			value = "false"
+		default:
+			return "", invalid("true or false")
+		}
+	case FieldEnum:
+		for _, v := range f.Values {
+			if strings.EqualFold(v, value) {
+				return v, nil
+			}
+		}
+		return "", invalid("one of " + strings.Join(f.Values, ", "))
+	}
+	return value, nil
+}
+
+// Field returns the value of the custom field called name, or "".
+func (c Contact) Field(name string) string {
+	return c.Fields[strings.ToLower(name)]
+}
+
+// SetField stores value under the custom field called name without any
+// check, see Schema.Set. An empty value removes the field.
+func (c *Contact) SetField(name, value string) {
+	name = strings.ToLower(name)
+	if value == "" {
+		delete(c.Fields, name)
+		if len(c.Fields) == 0 {
+			c.Fields = nil
+		}
+		return
+	}
+	if c.Fields == nil {
+		c.Fields = map[string]string{}
+	}
+	c.Fields[name] = value
+}
+
+// FieldNames returns the names of the custom fields set on any of contacts,
+// sorted.
+func FieldNames(contacts []Contact) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, c := range contacts {
+		for name := range c.Fields {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,211 @@
+package book
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// LockSuffix is appended to the file name to get the lock file shared by
+// every process using the book.
+const LockSuffix = ".lock"
+
+// FileStore keeps the whole address book in a single file that is read on
+// every call and rewritten on every change.
+//
+// Several processes may use the same file: reads hold a shared lock and
+// changes an exclusive one for the whole read-modify-write. The lock is
+// taken on a separate file because the book itself is replaced on every
+// write. On top of that every change checks the Version of the contacts it
+// is given against the file as it is now, as the Store interface tells.
+type FileStore struct {
+	path   string
+	decode func(io.Reader) ([]Contact, error)
+	encode func(io.Writer, []Contact) error
+}
+
+// NewCSVStore returns a store for the comma-separated file at path.
+func NewCSVStore(path string) *FileStore {
+	return &FileStore{path: path, decode: ReadCSV, encode: WriteCSV}
+}
+
+// NewJSONStore returns a store for the JSON document at path.
+func NewJSONStore(path string) *FileStore {
+	return &FileStore{path: path, decode: ReadJSON, encode: WriteJSON}
+}
+
+// Path returns the file backing the store.
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+func (s *FileStore) List() ([]Contact, error) {
+	return s.load()
+}
+
+func (s *FileStore) Get(id string) (Contact, error) {
+	contacts, err := s.load()
+	if err != nil {
+		return Contact{}, err
+	}
+	return get(contacts, id)
+}
+
+func (s *FileStore) Add(contact Contact) (Contact, error) {
+	err := s.modify(func(contacts []Contact) ([]Contact, error) {
+		var err error
+		contacts, contact, err = add(contacts, contact)
+		return contacts, err
+	})
+	if err != nil {
+		return Contact{}, err
+	}
+	return withVersion(contact), nil
+}
+
+func (s *FileStore) AddMany(added []Contact) ([]Contact, error) {
+	var stored []Contact
+	err := s.modify(func(contacts []Contact) ([]Contact, error) {
+		var err error
+		contacts, stored, err = addAll(contacts, added)
+		return contacts, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versioned(stored), nil
+}
+
+func (s *FileStore) Update(contact Contact) error {
+	return s.modify(func(contacts []Contact) ([]Contact, error) {
+		return replace(contacts, contact)
+	})
+}
+
+func (s *FileStore) UpdateMany(changed []Contact) error {
+	return s.modify(func(contacts []Contact) ([]Contact, error) {
+		return replaceAll(contacts, changed)
+	})
+}
+
+func (s *FileStore) Apply(changed, added []Contact) ([]Contact, error) {
+	var stored []Contact
+	err := s.modify(func(contacts []Contact) ([]Contact, error) {
+		var err error
+		contacts, stored, err = apply(contacts, changed, added)
+		return contacts, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versioned(stored), nil
+}
+
+func (s *FileStore) Delete(contact Contact) error {
+	return s.modify(func(contacts []Contact) ([]Contact, error) {
+		return remove(contacts, contact)
+	})
+}
+
+func (s *FileStore) Query(q Query) ([]Contact, error) {
+	contacts, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return filter(contacts, q), nil
+}
+
+// load reads every contact from the file under a shared lock and sets their
+// Version. A missing file is an empty book. Contacts written before IDs
+// existed are given one, and the file is rewritten right away so the IDs
+// stay stable from then on.
+func (s *FileStore) load() ([]Contact, error) {
+	unlock, err := s.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := s.read()
+	unlock()
+	if err != nil {
+		return nil, err
+	}
+	if !assignIDs(contacts) {
+		return versioned(contacts), nil
+	}
+
+	var upgraded []Contact
+	err = s.modify(func(contacts []Contact) ([]Contact, error) {
+		upgraded = contacts
+		return contacts, nil
+	})
+	return versioned(upgraded), err
+}
+
+// modify applies change to the book while holding the exclusive lock, so
+// that the contacts change sees are the ones saved.
+func (s *FileStore) modify(change func([]Contact) ([]Contact, error)) error {
+	unlock, err := s.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	contacts, err := s.read()
+	if err != nil {
+		return err
+	}
+	assignIDs(contacts)
+	contacts, err = change(contacts)
+	if err != nil {
+		return err
+	}
+	return s.save(contacts)
+}
+
+// read decodes the file without altering it.
+func (s *FileStore) read() ([]Contact, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := s.decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	return contacts, nil
+}
+
+// save replaces the content of the file with contacts, atomically.
+func (s *FileStore) save(contacts []Contact) error {
+	var buf bytes.Buffer
+	if err := s.encode(&buf, contacts); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, func(w io.Writer) error {
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+}
+
+// lock takes the advisory lock shared by every process using the book and
+// returns the function releasing it.
+func (s *FileStore) lock(exclusive bool) (func(), error) {
+	file, err := os.OpenFile(s.path+LockSuffix, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(file, exclusive); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("locking %s: %w", s.path, err)
+	}
+	return func() {
+		funlock(file)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,217 @@
+package book
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreConflicts(t *testing.T) {
+	dir := t.TempDir()
+	// two stores on one book, as two programs using the same file
+	stores := []struct {
+		name string
+		open func() (Store, Store)
+	}{
+		{"csv", func() (Store, Store) {
+			path := filepath.Join(dir, "contacts.txt")
+			return NewCSVStore(path), NewCSVStore(path)
+		}},
+		{"json", func() (Store, Store) {
+			path := filepath.Join(dir, "contacts.json")
+			return NewJSONStore(path), NewJSONStore(path)
+		}},
+		{"memory", func() (Store, Store) {
+			s := NewMemoryStore()
+			return s, s
+		}},
+	}
+	for _, tt := range stores {
+		t.Run(tt.name, func(t *testing.T) {
+			mine, theirs := tt.open()
+			added, err := mine.AddMany([]Contact{{Name: "Jane Doe"}, {Name: "John Roe"}, {Name: "Ann Poe"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			jane, john, ann := added[0], added[1], added[2]
+			if jane.Version == "" {
+				t.Fatal("AddMany returned contacts without a version")
+			}
+
+			changed := jane
+			changed.Notes = "changed elsewhere"
+			if err := theirs.Update(changed); err != nil {
+				t.Fatal(err)
+			}
+			// reading in the middle of an edit must not make the stale copy current
+			if _, err := mine.List(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := mine.Get(john.ID); err != nil {
+				t.Fatal(err)
+			}
+			jane.Title = "Engineer"
+			if err := mine.Update(jane); !errors.Is(err, ErrConflict) {
+				t.Errorf("Update of a stale contact = %v, want ErrConflict", err)
+			}
+			if err := mine.UpdateMany([]Contact{john, jane}); !errors.Is(err, ErrConflict) {
+				t.Errorf("UpdateMany with a stale contact = %v, want ErrConflict", err)
+			}
+			if err := mine.Delete(jane); !errors.Is(err, ErrConflict) {
+				t.Errorf("Delete of a stale contact = %v, want ErrConflict", err)
+			}
+
+			// contacts nobody else touched are changed as usual
+			john.Title = "Manager"
+			if err := mine.Update(john); err != nil {
+				t.Errorf("Update of a current contact: %v", err)
+			}
+			if err := theirs.Delete(ann); err != nil {
+				t.Fatal(err)
+			}
+			if err := mine.Update(ann); !errors.Is(err, ErrConflict) {
+				t.Errorf("Update of a deleted contact = %v, want ErrConflict", err)
+			}
+
+			fresh, err := mine.Get(jane.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fresh.Notes != "changed elsewhere" || fresh.Title != "" {
+				t.Errorf("stored contact = %+v, want the other change only", fresh)
+			}
+			fresh.Title = "Engineer"
+			if err := mine.Update(fresh); err != nil {
+				t.Errorf("Update after reading again: %v", err)
+			}
+			// a contact built by hand carries no version and is not checked
+			if err := mine.Update(Contact{ID: john.ID, Name: "John Roe"}); err != nil {
+				t.Errorf("Update without a version: %v", err)
+			}
+			if err := mine.Delete(Contact{ID: "missing"}); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Delete of an unknown ID = %v, want ErrNotFound", err)
+			}
+
+			list, err := mine.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 2 {
+				t.Errorf("List returned %d contacts, want 2", len(list))
+			}
+		})
+	}
+}
+
+func TestStoreApply(t *testing.T) {
+	stores := map[string]Store{
+		"csv":    NewCSVStore(filepath.Join(t.TempDir(), "contacts.txt")),
+		"memory": NewMemoryStore(),
+	}
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			jane, err := s.Add(Contact{Name: "Jane Doe"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			changed := jane
+			changed.Title = "Engineer"
+			// the duplicate ID makes the add fail, which must undo the update
+			if _, err := s.Apply([]Contact{changed}, []Contact{{Name: "John Roe"}, {ID: jane.ID, Name: "Copy"}}); err == nil {
+				t.Fatal("Apply with a duplicate ID succeeded")
+			}
+			list, err := s.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 1 || list[0].Title != "" {
+				t.Fatalf("book after a failed Apply = %+v, want it unchanged", list)
+			}
+
+			stored, err := s.Apply([]Contact{changed}, []Contact{{Name: "John Roe"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(stored) != 1 || stored[0].ID == "" || stored[0].Version == "" {
+				t.Errorf("Apply returned %+v, want John as stored", stored)
+			}
+			list, err = s.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list) != 2 || list[0].Title != "Engineer" || list[1].Name != "John Roe" {
+				t.Errorf("book after Apply = %+v", list)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "contacts.txt")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, "new")
+	assertFile(t, path+BackupSuffix, "old")
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("mode after rewrite = %v (%v), want 0600", info.Mode().Perm(), err)
+	}
+
+	failure := errors.New("disk full")
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("writeFileAtomic = %v, want %v", err, failure)
+	}
+	assertFile(t, path, "new")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Errorf("files left after a failed write: %v, want the book and its backup", names)
+	}
+}
+
+func TestWriteFileAtomicCreates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contacts.json")
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		_, err := io.WriteString(w, "{}")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, "{}")
+	if _, err := os.Stat(path + BackupSuffix); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("backup of a new file: %v, want none", err)
+	}
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s holds %q, want %q", filepath.Base(path), data, want)
+	}
+}
@@ -0,0 +1,94 @@
+package book
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icsLeapYear stands in for the unknown year of an event, so that
+// February 29 is a valid start date.
+const icsLeapYear = 2000
+
+// WriteICS writes the events of contacts to w as an iCalendar feed
+// (RFC 5545) of yearly all-day events. An event starts on its date, or in
+// icsLeapYear when the year is unknown. A February 29 event recurs on the
+// last day of February so that it shows up in every year. now is the time
+// stamp of the feed.
+func WriteICS(w io.Writer, contacts []Contact, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(format string, a ...any) {
+		writeContentLine(bw, fmt.Sprintf(format, a...))
+	}
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//contact-book//Contacts//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:Contacts")
+	for _, c := range contacts {
+		for _, e := range c.Events {
+			if e.Date.IsZero() {
+				continue
+			}
+			start := e.Date
+			if start.Year == 0 {
+				start.Year = icsLeapYear
+			}
+			line("BEGIN:VEVENT")
+			line("UID:%s-%s@contact-book", c.ID, e.Label)
+			line("DTSTAMP:%s", stamp)
+			line("DTSTART;VALUE=DATE:%04d%02d%02d", start.Year, start.Month, start.Day)
+			if e.Date.Month == time.February && e.Date.Day == 29 {
+				line("RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1")
+			} else {
+				line("RRULE:FREQ=YEARLY")
+			}
+			line("SUMMARY:%s", escapeText(c.DisplayName(GivenFirst)+"'s "+e.Label))
+			if e.Date.Year > 0 && e.Label == EventBirthday {
+				line("DESCRIPTION:Born in %d", e.Date.Year)
+			} else if e.Date.Year > 0 {
+				line("DESCRIPTION:Since %d", e.Date.Year)
+			}
+			line("CATEGORIES:%s", escapeText(capitalize(e.Label)))
+			line("TRANSP:TRANSPARENT")
+			line("END:VEVENT")
+		}
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// writeContentLine ends a content line of an iCalendar or vCard file with
+// CRLF and folds it into lines of at most 75 octets, without splitting a
+// character.
+func writeContentLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// the space starting a continuation line counts too
+		limit = 74
+	}
+	w.WriteString(line + "\r\n")
+}
+
+// escapeText escapes a TEXT value of an iCalendar or vCard file.
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,44 @@
+package book
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// shortIDLen is the number of leading ID characters shown in tables.
+const shortIDLen = 8
+
+// NewID returns a random RFC 4122 version 4 UUID.
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("book: reading random bytes: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// ShortID returns the leading characters of the contact ID, enough to tell
+// contacts apart in a table.
+func (c Contact) ShortID() string {
+	if len(c.ID) <= shortIDLen {
+		return c.ID
+	}
+	return c.ID[:shortIDLen]
+}
+
+// assignIDs gives an ID to every contact that has none or whose ID is
+// already used by an earlier contact. It reports whether anything changed.
+func assignIDs(contacts []Contact) bool {
+	changed := false
+	seen := map[string]bool{}
+	for i := range contacts {
+		if contacts[i].ID == "" || seen[contacts[i].ID] {
+			contacts[i].ID = NewID()
+			changed = true
+		}
+		seen[contacts[i].ID] = true
+	}
+	return changed
+}
@@ -0,0 +1,21 @@
+package book
+
+import "fmt"
+
+// Rejected is an entry of an imported file that did not become a contact,
+// and the reason why.
+type Rejected struct {
+	// Line is the line of the file the entry starts on.
+	Line int
+	// Name is the name the entry gives, when it has one.
+	Name   string
+	Reason error
+}
+
+// String renders the rejection as "line N (name): reason".
+func (r Rejected) String() string {
+	if r.Name == "" {
+		return fmt.Sprintf("line %d: %v", r.Line, r.Reason)
+	}
+	return fmt.Sprintf("line %d (%s): %v", r.Line, r.Name, r.Reason)
+}
@@ -0,0 +1,127 @@
+package book
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of interaction offered by the front-ends. Any other label may be
+// used.
+const (
+	InteractionCalled   = "called"
+	InteractionMet      = "met"
+	InteractionEmailed  = "emailed"
+	InteractionMessaged = "messaged"
+)
+
+// Interaction is one entry of the log of calls, meetings and messages
+// exchanged with a contact.
+type Interaction struct {
+	At   time.Time `json:"at"`
+	Kind string    `json:"kind"`
+	Text string    `json:"text,omitempty"`
+}
+
+// NewInteraction validates an entry of the log. Kind is a label such as
+// "called"; text is a short summary kept on one line. The time is kept in
+// UTC to the second.
+func NewInteraction(at time.Time, kind, text string) (Interaction, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if !validLabel(kind) {
+		return Interaction{}, fmt.Errorf("invalid interaction kind %q, use a word such as called, met or emailed", kind)
+	}
+	if at.IsZero() {
+		return Interaction{}, fmt.Errorf("interaction %s has no time", kind)
+	}
+	return Interaction{
+		At:   at.UTC().Truncate(time.Second),
+		Kind: kind,
+		Text: strings.Join(strings.Fields(text), " "),
+	}, nil
+}
+
+// ParseInteraction reads an entry written as String writes it:
+// "2026-10-18T09:30:00Z called: left a message".
+func ParseInteraction(input string) (Interaction, error) {
+	stamp, rest, _ := strings.Cut(strings.TrimSpace(input), " ")
+	at, err := time.Parse(time.RFC3339, stamp)
+	if err != nil {
+		return Interaction{}, fmt.Errorf("interaction %q does not start with a time", input)
+	}
+	kind, text, _ := strings.Cut(rest, ":")
+	return NewInteraction(at, kind, text)
+}
+
+// String renders the entry on one line, as ParseInteraction reads it.
+func (i Interaction) String() string {
+	s := i.At.UTC().Format(time.RFC3339) + " " + i.Kind
+	if i.Text != "" {
+		s += ": " + i.Text
+	}
+	return s
+}
+
+// JoinInteractions renders the log one entry per line.
+func JoinInteractions(log []Interaction) string {
+	lines := make([]string, len(log))
+	for i, entry := range log {
+		lines[i] = entry.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NormalizeNotes makes every line break of free text a "\n" and trims the
+// blank lines and spaces around it.
+func NormalizeNotes(text string) string {
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// AddNote appends a paragraph to the notes of the contact.
+func (c *Contact) AddNote(text string) {
+	text = NormalizeNotes(text)
+	if text == "" {
+		return
+	}
+	if c.Notes == "" {
+		c.Notes = text
+		return
+	}
+	c.Notes += "\n" + text
+}
+
+// LogInteraction adds entry to the log of the contact, which is kept
+// oldest first.
+func (c *Contact) LogInteraction(entry Interaction) {
+	c.Interactions = append(c.Interactions, entry)
+	sort.SliceStable(c.Interactions, func(i, j int) bool {
+		return c.Interactions[i].At.Before(c.Interactions[j].At)
+	})
+}
+
+// LastContacted returns the time of the latest entry of the log, or the
+// zero time when the log is empty.
+func (c Contact) LastContacted() time.Time {
+	var last time.Time
+	for _, entry := range c.Interactions {
+		if entry.At.After(last) {
+			last = entry.At
+		}
+	}
+	return last
+}
+
+// SortByLastContacted sorts contacts by the time they were last contacted,
+// the most recent first. Contacts never contacted come last, in their
+// current order.
+func SortByLastContacted(contacts []Contact) {
+	sort.SliceStable(contacts, func(i, j int) bool {
+		return contacts[i].LastContacted().After(contacts[j].LastContacted())
+	})
+}
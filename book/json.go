@@ -0,0 +1,248 @@
+package book
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// jsonVersion is the version of the document written by WriteJSON.
+// Version 1 held a single "email" and "mobile" per contact.
+const jsonVersion = 2
+
+// JSONSchemaID identifies the JSON Schema of the documents WriteJSON
+// writes, in their "$schema" field. It changes with the version of the
+// document.
+const JSONSchemaID = "urn:contact-book:contacts:2"
+
+// JSONSchema is the JSON Schema, draft 2020-12, of the documents WriteJSON
+// writes and ReadJSONContacts reads.
+//
+//go:embed contacts.schema.json
+var JSONSchema []byte
+
+// jsonDocument is the on-disk layout of a JSON address book.
+type jsonDocument struct {
+	Schema   string    `json:"$schema"`
+	Version  int       `json:"version"`
+	Contacts []Contact `json:"contacts"`
+}
+
+// jsonStoredDocument reads documents of every version.
+type jsonStoredDocument struct {
+	Version  int                 `json:"version"`
+	Contacts []jsonStoredContact `json:"contacts"`
+}
+
+type jsonStoredContact struct {
+	Contact
+	Email  string `json:"email"`
+	Mobile string `json:"mobile"`
+}
+
+// ReadJSON parses an address book document written by WriteJSON. An empty
+// input is an empty address book.
+func ReadJSON(r io.Reader) ([]Contact, error) {
+	var doc jsonStoredDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if doc.Version > jsonVersion {
+		return nil, fmt.Errorf("unsupported document version %d", doc.Version)
+	}
+	contacts := make([]Contact, len(doc.Contacts))
+	for i, stored := range doc.Contacts {
+		c := stored.Contact
+		if stored.Email != "" {
+			c.Emails = append(c.Emails, Email{Address: stored.Email})
+		}
+		if stored.Mobile != "" {
+			c.Phones = append(c.Phones, Phone{Label: LabelMobile, Number: stored.Mobile})
+		}
+		c.Normalize()
+		contacts[i] = c
+	}
+	return contacts, nil
+}
+
+// WriteJSON writes contacts to w as an indented JSON document.
+func WriteJSON(w io.Writer, contacts []Contact) error {
+	if contacts == nil {
+		contacts = []Contact{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonDocument{Schema: JSONSchemaID, Version: jsonVersion, Contacts: contacts})
+}
+
+// ReadJSONContacts reads a document written by WriteJSON, or generated by
+// another program, and checks it against JSONSchema. Phone numbers
+// lacking a country code are read as numbers of region and stored in
+// E.164 form, and custom fields are set through fields, which must declare
+// them. A contact that does not match is returned as rejected, the reason
+// naming every field at fault with its line when it is not the line the
+// contact starts on, such as `emails[0].address (line 12): invalid email
+// format: "jane@"`. The error reports a document that cannot be parsed, or
+// whose layout or version is wrong.
+func ReadJSONContacts(r io.Reader, region string, fields Schema) ([]Contact, []Rejected, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = []byte(strings.TrimPrefix(string(data), "\ufeff"))
+	doc, err := parseJSON(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(JSONSchema, &schema); err != nil {
+		return nil, nil, fmt.Errorf("reading the schema: %w", err)
+	}
+	v := jsonValidator{root: schema, formats: map[string]func(string) error{
+		"email": func(s string) error {
+			if !IsValidEmail(s) {
+				return fmt.Errorf("%w: %q", ErrInvalidEmail, s)
+			}
+			return nil
+		},
+		"phone": func(s string) error {
+			_, err := ParsePhone(s, region)
+			return err
+		},
+		"day": func(s string) error {
+			_, err := ParseDate(s)
+			return err
+		},
+		"date-time": func(s string) error {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return fmt.Errorf("invalid time %q, want an RFC 3339 time such as 2026-10-18T09:30:00Z", s)
+			}
+			return nil
+		},
+	}}
+
+	// errors within a contact reject that contact only
+	byContact := map[int][]schemaError{}
+	var errs []error
+	for _, e := range v.validate(schema, doc, "") {
+		var i int
+		if _, err := fmt.Sscanf(e.Path, "contacts[%d]", &i); err == nil {
+			e.Path = strings.TrimPrefix(strings.TrimPrefix(e.Path, fmt.Sprintf("contacts[%d]", i)), ".")
+			byContact[i] = append(byContact[i], e)
+			continue
+		}
+		errs = append(errs, e)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+
+	list, _ := doc.field("contacts")
+	var contacts []Contact
+	var rejected []Rejected
+	for i, n := range list.fields {
+		var name string
+		if field, ok := n.field("name"); ok {
+			name, _ = field.value.(string)
+		}
+		var c Contact
+		errs := byContact[i]
+		if len(errs) == 0 {
+			if err := json.Unmarshal(data[n.start:n.end], &c); err != nil {
+				errs = append(errs, schemaError{Line: n.line, Message: err.Error()})
+			}
+			errs = append(errs, setJSONFields(&c, n, fields)...)
+		}
+		if len(errs) > 0 {
+			rejected = append(rejected, Rejected{Line: n.line, Name: name, Reason: joinSchemaErrors(errs, n.line)})
+			continue
+		}
+		if errs := normalizeJSONContact(&c, n, region); len(errs) > 0 {
+			rejected = append(rejected, Rejected{Line: n.line, Name: name, Reason: joinSchemaErrors(errs, n.line)})
+			continue
+		}
+		contacts = append(contacts, c)
+	}
+	return contacts, rejected, nil
+}
+
+// normalizeJSONContact brings a contact read from the document n holds into
+// the form the other importers give: a name without parts capitalized and
+// split with SetName, the name made from its parts otherwise, phones in
+// E.164 form, and tags, addresses and interactions made with NormalizeTag,
+// NewAddress and NewInteraction. It reports the entries that cannot be,
+// such as an address with nothing but a label.
+func normalizeJSONContact(c *Contact, n jsonNode, region string) []schemaError {
+	if c.NameParts.IsZero() {
+		c.SetName(c.Name)
+	} else {
+		// parts are kept as written, so a nickname such as "JJ" survives
+		c.Name = c.NameParts.Format(GivenFirst)
+	}
+	for j := range c.Phones {
+		c.Phones[j].Number, _ = ParsePhone(c.Phones[j].Number, region)
+	}
+	for j, tag := range c.Tags {
+		c.Tags[j], _ = NormalizeTag(tag)
+	}
+	var errs []schemaError
+	fail := func(list string, j int, err error) {
+		entries, _ := n.field(list)
+		errs = append(errs, schemaError{Path: fmt.Sprintf("%s[%d]", list, j), Line: entries.fields[j].line, Message: err.Error()})
+	}
+	for j, a := range c.Addresses {
+		address, err := NewAddress(a.Label, a.Street, a.City, a.Region, a.PostalCode, a.Country)
+		if err != nil {
+			fail("addresses", j, err)
+			continue
+		}
+		c.Addresses[j] = address
+	}
+	for j, entry := range c.Interactions {
+		interaction, err := NewInteraction(entry.At, entry.Kind, entry.Text)
+		if err != nil {
+			fail("interactions", j, err)
+			continue
+		}
+		c.Interactions[j] = interaction
+	}
+	c.Normalize()
+	return errs
+}
+
+// setJSONFields stores the custom fields of the contact n holds through
+// fields, as edits do, and reports those it does not declare or whose
+// value does not fit their type.
+func setJSONFields(c *Contact, n jsonNode, fields Schema) []schemaError {
+	values, _ := n.field("fields")
+	c.Fields = nil
+	var errs []schemaError
+	for i, name := range values.keys {
+		value, _ := values.fields[i].value.(string)
+		if err := fields.Set(c, name, value); err != nil {
+			errs = append(errs, schemaError{Path: joinPath("fields", name), Line: values.fields[i].line, Message: err.Error()})
+		}
+	}
+	return errs
+}
+
+// joinSchemaErrors joins the errors of a contact on one line, separated
+// by semicolons, giving the line of those that are not on the line the
+// contact starts on.
+func joinSchemaErrors(errs []schemaError, line int) error {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		if e.Line == line {
+			e.Line = 0
+		}
+		messages[i] = e.Error()
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
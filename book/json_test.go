@@ -0,0 +1,171 @@
+package book
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFields = Schema{Fields: []FieldDef{
+	{Name: "slack", Type: FieldText},
+	{Name: "employee", Type: FieldNumber},
+}}
+
+func TestReadJSONContactsRoundTrip(t *testing.T) {
+	at := time.Date(2026, 9, 30, 10, 0, 0, 0, time.UTC)
+	jane := Contact{
+		ID:           NewID(),
+		Name:         "Jane Doe",
+		NameParts:    NameParts{Given: "Jane", Family: "Doe", Nickname: "JJ"},
+		Emails:       []Email{{Label: "work", Address: "jane@acme.com", Primary: true}},
+		Phones:       []Phone{{Label: "mobile", Number: "+971501234567", Primary: true}},
+		Addresses:    []Address{{Label: "home", Street: "12 Palm Street\nApt 4", City: "Dubai", Country: "AE"}},
+		Tags:         []string{"family"},
+		Favorite:     true,
+		Events:       []Event{{Label: EventBirthday, Date: Date{Year: 1990, Month: 5, Day: 17}}},
+		Notes:        "Met at GITEX.\nPrefers WhatsApp.",
+		Interactions: []Interaction{{At: at, Kind: "met", Text: "lunch"}},
+		Fields:       map[string]string{"slack": "@jane"},
+		Created:      at,
+		Updated:      at,
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, []Contact{jane}); err != nil {
+		t.Fatal(err)
+	}
+	contacts, rejected, err := ReadJSONContacts(&buf, "AE", testFields)
+	if err != nil || len(rejected) > 0 {
+		t.Fatalf("ReadJSONContacts = %v, %v", rejected, err)
+	}
+	if len(contacts) != 1 || !reflect.DeepEqual(contacts[0], jane) {
+		t.Errorf("read back %+v, want %+v", contacts, jane)
+	}
+}
+
+func TestReadJSONContactsRejects(t *testing.T) {
+	tests := []struct {
+		contact string
+		want    string
+	}{
+		{`{"name": "A", "emails": [{"address": "jane@"}]}`, `line 4 (A): emails[0].address: invalid email format: "jane@"`},
+		{`{"name": "A",` + "\n" + `"phones": [{"number": "12"}]}`, `line 4 (A): phones[0].number (line 5): invalid phone number`},
+		{`{"name": "A", "nick": "x"}`, `line 4 (A): nick: unknown field`},
+		{`{"emails": []}`, `line 4: missing "name"`},
+		{`{"name": 5}`, `line 4: name: want a string, got number`},
+		{`{"name": "A", "events": [{"label": "birthday", "date": "1990-02-30"}]}`, `events[0].date: invalid date "1990-02-30", no such day`},
+		{`{"name": "A", "tags": ["a;b"]}`, `tags[0]: "a;b" does not match`},
+		{`{"name": "A", "interactions": [{"at": "yesterday", "kind": "called"}]}`, `interactions[0].at: invalid time "yesterday"`},
+		{`{"name": "A", "emails": [{"address": "a@b.com", "label": "Work"}]}`, `emails[0].label: "Work" does not match`},
+		{`{"name": "A", "fields": {"Slack Handle": "@a"}}`, `fields.Slack Handle: "Slack Handle" does not match`},
+		{`{"name": "A", "fields": {"x;y": "1"}}`, `fields.x;y: "x;y" does not match`},
+		{`{"name": "A", "fields": {"team": "sales"}}`, `fields.team: invalid custom field: no field "team" is declared`},
+		{`{"name": "A", "fields": {"employee": "abc"}}`, `fields.employee: invalid custom field: employee wants a number`},
+		{`{"name": "A", "addresses": [{}]}`, `addresses[0]: must not be empty`},
+		{`{"name": "A",` + "\n" + `"addresses": [{"label": "home"}]}`, `addresses[0] (line 5): address is empty`},
+		{`{"name": "A", "interactions": [{"at": "0001-01-01T00:00:00Z", "kind": "met"}]}`, `interactions[0]: interaction met has no time`},
+		{`{"name": "A", "modified": {"a;b=c": "2024-05-01T10:00:00Z"}}`, `modified.a;b=c: "a;b=c" does not match`},
+		{`{"name": "A", "modified": {"fields.Slack": "2024-05-01T10:00:00Z"}}`, `modified.fields.Slack: "fields.Slack" does not match`},
+	}
+	for _, tt := range tests {
+		doc := "{\n\"version\": 2,\n\"contacts\": [\n" + tt.contact + "\n]}"
+		contacts, rejected, err := ReadJSONContacts(strings.NewReader(doc), "AE", testFields)
+		if err != nil {
+			t.Errorf("%s: %v", tt.contact, err)
+			continue
+		}
+		if len(contacts) > 0 || len(rejected) != 1 {
+			t.Errorf("%s: read %v, rejected %v, want one rejection", tt.contact, contacts, rejected)
+			continue
+		}
+		if got := rejected[0].String(); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: rejected as %q, want %q", tt.contact, got, tt.want)
+		}
+	}
+}
+
+func TestReadJSONContactsDocumentErrors(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want string
+	}{
+		{"", "no JSON document found"},
+		{`{"version": 2, "contacts": [`, "unexpected end of JSON input"},
+		{"{\"version\": 2,\n \"contacts\": [\n {\"name\": \"a\",}]}", "line 3: invalid character"},
+		{`[]`, "line 1: want an object, got array"},
+		{`{"version": 3, "contacts": []}`, "version (line 1): want 2"},
+		{`{"version": 2, "contacts": {}}`, "contacts (line 1): want an array, got object"},
+		{`{"version": 2, "contacts": [], "x": 1}`, "x (line 1): unknown field"},
+		{`{"version": 2, "contacts": []} {}`, "data after the document"},
+	}
+	for _, tt := range tests {
+		_, _, err := ReadJSONContacts(strings.NewReader(tt.doc), "AE", testFields)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ReadJSONContacts(%q) error = %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+}
+
+func TestReadJSONContactsNormalizes(t *testing.T) {
+	doc := `{"version": 2, "contacts": [{"name": "A", "phones": [{"number": "050 123 4567"}]}]}`
+	contacts, rejected, err := ReadJSONContacts(strings.NewReader(doc), "AE", Schema{})
+	if err != nil || len(rejected) > 0 {
+		t.Fatalf("ReadJSONContacts = %v, %v", rejected, err)
+	}
+	if got := contacts[0].Phones; len(got) != 1 || got[0].Number != "+971501234567" || !got[0].Primary {
+		t.Errorf("phones = %+v, want +971501234567 as the primary one", got)
+	}
+
+	// names, addresses and interactions come out as the other importers make them
+	doc = `{"version": 2, "contacts": [
+{"name": "jane doe",
+ "addresses": [{"label": "home", "city": " dubai "}],
+ "interactions": [{"at": "2024-05-01T10:00:00Z", "kind": "met", "text": "lunch\nat noon"}]},
+{"name": "x", "nameParts": {"given": "John", "family": "van der Berg", "nickname": "JJ"}}]}`
+	contacts, rejected, err = ReadJSONContacts(strings.NewReader(doc), "AE", Schema{})
+	if err != nil || len(rejected) > 0 {
+		t.Fatalf("ReadJSONContacts = %v, %v", rejected, err)
+	}
+	jane, john := contacts[0], contacts[1]
+	if jane.Name != "Jane Doe" || jane.NameParts != (NameParts{Given: "Jane", Family: "Doe"}) {
+		t.Errorf("name = %q, %+v, want Jane Doe split in two", jane.Name, jane.NameParts)
+	}
+	if john.Name != "John van der Berg" || john.NameParts.Nickname != "JJ" {
+		t.Errorf("name from parts = %q, %+v, want John van der Berg as written", john.Name, john.NameParts)
+	}
+	if got := jane.Addresses; len(got) != 1 || got[0].City != "dubai" {
+		t.Errorf("addresses = %+v, want the city trimmed", got)
+	}
+	if got := jane.Interactions; len(got) != 1 || got[0].Text != "lunch at noon" {
+		t.Errorf("interactions = %+v, want the text on one line", got)
+	}
+}
+
+func TestJSONSchemaModifiedNames(t *testing.T) {
+	var schema struct {
+		Defs map[string]struct {
+			Properties struct {
+				Modified struct {
+					PropertyNames struct {
+						Pattern string `json:"pattern"`
+					} `json:"propertyNames"`
+				} `json:"modified"`
+			} `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(JSONSchema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile(schema.Defs["contact"].Properties.Modified.PropertyNames.Pattern)
+	for _, field := range trackedFields {
+		if !re.MatchString(field.name) {
+			t.Errorf("schema rejects the modified time of %s", field.name)
+		}
+	}
+	if !re.MatchString("fields.slack") {
+		t.Error("schema rejects the modified time of a custom field")
+	}
+}
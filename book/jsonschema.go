@@ -0,0 +1,293 @@
+package book
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsonNode is a parsed JSON value that remembers where it stands in the
+// document, so that errors can point at the line at fault.
+type jsonNode struct {
+	// kind is the JSON Schema type: object, array, string, number,
+	// boolean or null.
+	kind  string
+	value any // the string, json.Number or bool of a scalar
+	keys  []string
+	// fields holds the values of an object in the order of keys, and the
+	// items of an array.
+	fields []jsonNode
+	// start and end are the offsets of the value in the document, and
+	// line the line it starts on.
+	start, end int
+	line       int
+}
+
+// parseJSON parses a whole JSON document. A syntax error is reported with
+// its line.
+func parseJSON(data []byte) (jsonNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := parseJSONNode(dec, data)
+	if err == nil {
+		if _, extra := dec.Token(); extra != io.EOF {
+			err = fmt.Errorf("line %d: data after the document", lineAt(data, int(dec.InputOffset())))
+		}
+		return n, err
+	}
+	var syntax *json.SyntaxError
+	switch {
+	case errors.As(err, &syntax):
+		return jsonNode{}, fmt.Errorf("line %d: %v", lineAt(data, int(syntax.Offset)), err)
+	case errors.Is(err, io.EOF) && len(bytes.TrimSpace(data)) == 0:
+		return jsonNode{}, errors.New("no JSON document found")
+	case errors.Is(err, io.EOF):
+		return jsonNode{}, errors.New("the document ends too early")
+	}
+	return jsonNode{}, fmt.Errorf("line %d: %v", lineAt(data, int(dec.InputOffset())), err)
+}
+
+func parseJSONNode(dec *json.Decoder, data []byte) (jsonNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return jsonNode{}, err
+	}
+	end := int(dec.InputOffset())
+	n := jsonNode{value: tok, end: end}
+	switch t := tok.(type) {
+	case json.Delim:
+		n.start, n.value = end-1, nil
+		n.kind = "array"
+		if t == '{' {
+			n.kind = "object"
+		}
+		for dec.More() {
+			if n.kind == "object" {
+				key, err := dec.Token()
+				if err != nil {
+					return jsonNode{}, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			field, err := parseJSONNode(dec, data)
+			if err != nil {
+				return jsonNode{}, err
+			}
+			n.fields = append(n.fields, field)
+		}
+		if _, err := dec.Token(); err != nil {
+			return jsonNode{}, err
+		}
+		n.end = int(dec.InputOffset())
+	case string:
+		n.kind = "string"
+		n.start = bytes.LastIndexByte(data[:end-1], '"')
+	case json.Number:
+		n.kind, n.start = "number", end-len(t)
+	case bool:
+		n.kind, n.start = "boolean", end-len(strconv.FormatBool(t))
+	default:
+		n.kind, n.start = "null", end-len("null")
+	}
+	n.line = lineAt(data, n.start)
+	return n, nil
+}
+
+// lineAt returns the line, counted from 1, of the offset in data.
+func lineAt(data []byte, offset int) int {
+	return bytes.Count(data[:min(max(offset, 0), len(data))], []byte("\n")) + 1
+}
+
+// field returns the value of key in an object.
+func (n jsonNode) field(key string) (jsonNode, bool) {
+	for i, k := range n.keys {
+		if k == key {
+			return n.fields[i], true
+		}
+	}
+	return jsonNode{}, false
+}
+
+// schemaError is a value that does not match the schema: the path to it,
+// such as "emails[0].address", its line and what is wrong.
+type schemaError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// Error renders the error as "path (line N): message", leaving out what
+// is not known: the path of the document itself and a line of 0.
+func (e schemaError) Error() string {
+	where := e.Path
+	switch {
+	case e.Line > 0 && where != "":
+		where += fmt.Sprintf(" (line %d)", e.Line)
+	case e.Line > 0:
+		where = fmt.Sprintf("line %d", e.Line)
+	}
+	if where == "" {
+		return e.Message
+	}
+	return where + ": " + e.Message
+}
+
+// jsonValidator checks documents against a JSON Schema. It knows the
+// keywords the schema of the contact book uses: $ref to its own $defs,
+// type, const, enum, required, properties, additionalProperties,
+// propertyNames, minProperties, items, minLength, pattern and format, the
+// formats being checked by the functions in formats.
+type jsonValidator struct {
+	root    map[string]any
+	formats map[string]func(string) error
+}
+
+func (v jsonValidator) validate(schema map[string]any, n jsonNode, path string) []schemaError {
+	var errs []schemaError
+	fail := func(format string, args ...any) {
+		errs = append(errs, schemaError{Path: path, Line: n.line, Message: fmt.Sprintf(format, args...)})
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		errs = append(errs, v.validate(v.resolve(ref), n, path)...)
+	}
+	if want, ok := schema["type"].(string); ok && n.kind != want && !(want == "integer" && n.kind == "number") {
+		fail("want %s %s, got %s", article(want), want, n.kind)
+		return errs
+	}
+	if want, ok := schema["const"]; ok && !sameValue(n, want) {
+		fail("want %v", jsonText(want))
+	}
+	if values, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, want := range values {
+			found = found || sameValue(n, want)
+		}
+		if !found {
+			fail("want one of %s", jsonText(values))
+		}
+	}
+	switch n.kind {
+	case "object":
+		properties, _ := schema["properties"].(map[string]any)
+		if minimum, ok := schema["minProperties"].(float64); ok && len(n.keys) < int(minimum) {
+			if minimum == 1 {
+				fail("must not be empty")
+			} else {
+				fail("fewer than %v fields", minimum)
+			}
+		}
+		for _, key := range stringList(schema["required"]) {
+			if _, ok := n.field(key); !ok {
+				fail("missing %q", key)
+			}
+		}
+		for i, key := range n.keys {
+			fieldPath := joinPath(path, key)
+			if names, ok := schema["propertyNames"].(map[string]any); ok {
+				keyNode := jsonNode{kind: "string", value: key, line: n.fields[i].line}
+				errs = append(errs, v.validate(names, keyNode, fieldPath)...)
+			}
+			if sub, ok := properties[key].(map[string]any); ok {
+				errs = append(errs, v.validate(sub, n.fields[i], fieldPath)...)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					errs = append(errs, schemaError{Path: fieldPath, Line: n.fields[i].line, Message: "unknown field"})
+				}
+			case map[string]any:
+				errs = append(errs, v.validate(extra, n.fields[i], fieldPath)...)
+			}
+		}
+	case "array":
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range n.fields {
+				errs = append(errs, v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case "string":
+		s := n.value.(string)
+		if minimum, ok := schema["minLength"].(float64); ok && utf8.RuneCountInString(s) < int(minimum) {
+			if minimum == 1 {
+				fail("must not be empty")
+			} else {
+				fail("shorter than %v characters", minimum)
+			}
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(s) {
+				fail("%q does not match %s", s, pattern)
+			}
+		}
+		if format, ok := schema["format"].(string); ok && v.formats[format] != nil {
+			if err := v.formats[format](s); err != nil {
+				fail("%v", err)
+			}
+		}
+	}
+	return errs
+}
+
+// resolve returns the definition a "#/$defs/name" reference points to.
+func (v jsonValidator) resolve(ref string) map[string]any {
+	defs, _ := v.root["$defs"].(map[string]any)
+	def, _ := defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	return def
+}
+
+// sameValue reports whether n holds want, a value of the schema.
+func sameValue(n jsonNode, want any) bool {
+	switch w := want.(type) {
+	case float64:
+		number, ok := n.value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := number.Float64()
+		return err == nil && f == w
+	case string, bool:
+		return n.value == w
+	case nil:
+		return n.kind == "null"
+	}
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func stringList(v any) []string {
+	list, _ := v.([]any)
+	var s []string
+	for _, item := range list {
+		if str, ok := item.(string); ok {
+			s = append(s, str)
+		}
+	}
+	sort.Strings(s)
+	return s
+}
+
+func jsonText(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func article(word string) string {
+	if strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
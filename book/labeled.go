@@ -0,0 +1,140 @@
+package book
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Common labels for emails and phones. Any other short word may be used.
+const (
+	LabelHome   = "home"
+	LabelWork   = "work"
+	LabelMobile = "mobile"
+	LabelOther  = "other"
+)
+
+// Email is one email address of a contact.
+type Email struct {
+	Label   string `json:"label,omitempty"`
+	Address string `json:"address"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Phone is one phone number of a contact, in E.164 form.
+type Phone struct {
+	Label   string `json:"label,omitempty"`
+	Number  string `json:"number"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// String renders the email as ParseEmail reads it.
+func (e Email) String() string {
+	return formatEntry(e.Label, e.Address, e.Primary)
+}
+
+// String renders the phone as ParsePhoneEntry reads it.
+func (p Phone) String() string {
+	return formatEntry(p.Label, p.Number, p.Primary)
+}
+
+// ParseEmail reads an email written as "[*][label:]address", for example
+// "work:jane@acme.com". A leading "*" marks the primary address.
+func ParseEmail(input string) (Email, error) {
+	label, address, primary, err := parseEntry(input)
+	if err != nil {
+		return Email{}, err
+	}
+	if !IsValidEmail(address) {
+		return Email{}, fmt.Errorf("%w: %q", ErrInvalidEmail, address)
+	}
+	return Email{Label: label, Address: address, Primary: primary}, nil
+}
+
+// ParsePhoneEntry reads a phone written as "[*][label:]number", for example
+// "work:+44 20 7946 0958", and converts the number with ParsePhone. A
+// leading "*" marks the primary number.
+func ParsePhoneEntry(input, region string) (Phone, error) {
+	label, number, primary, err := parseEntry(input)
+	if err != nil {
+		return Phone{}, err
+	}
+	e164, err := ParsePhone(number, region)
+	if err != nil {
+		return Phone{}, err
+	}
+	return Phone{Label: label, Number: e164, Primary: primary}, nil
+}
+
+// SplitEntries splits a list of entries separated by semicolons, as typed
+// in a single prompt or stored in one CSV cell. A semicolon escaped with a
+// backslash, as in an address, does not separate entries.
+func SplitEntries(list string) []string {
+	var entries []string
+	for _, entry := range splitEscaped(list, ';') {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// JoinEmails renders emails as one semicolon separated list. The primary
+// mark is left out when there is nothing to choose from.
+func JoinEmails(emails []Email) string {
+	parts := make([]string, len(emails))
+	for i, e := range emails {
+		parts[i] = formatEntry(e.Label, e.Address, e.Primary && len(emails) > 1)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// JoinPhones renders phones as one semicolon separated list. The primary
+// mark is left out when there is nothing to choose from.
+func JoinPhones(phones []Phone) string {
+	parts := make([]string, len(phones))
+	for i, p := range phones {
+		parts[i] = formatEntry(p.Label, p.Number, p.Primary && len(phones) > 1)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseEntry splits "[*][label:]value". Labels are single lower-case words.
+func parseEntry(input string) (label, value string, primary bool, err error) {
+	input = strings.TrimSpace(input)
+	if rest, ok := strings.CutPrefix(input, "*"); ok {
+		primary = true
+		input = strings.TrimSpace(rest)
+	}
+	value = input
+	if before, after, ok := strings.Cut(input, ":"); ok {
+		label = strings.ToLower(strings.TrimSpace(before))
+		value = strings.TrimSpace(after)
+		if !validLabel(label) {
+			return "", "", false, fmt.Errorf("invalid label %q, use letters, digits and dashes", before)
+		}
+	}
+	return label, value, primary, nil
+}
+
+func formatEntry(label, value string, primary bool) string {
+	s := value
+	if label != "" {
+		s = label + ":" + s
+	}
+	if primary {
+		s = "*" + s
+	}
+	return s
+}
+
+func validLabel(label string) bool {
+	if label == "" {
+		return false
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package book
+
+import "os"
+
+// flock is a no-op on platforms without flock(2); the version check done by
+// FileStore still catches most concurrent edits there.
+func flock(f *os.File, exclusive bool) error {
+	return nil
+}
+
+func funlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,136 @@
+package book
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// MemoryStore keeps contacts in memory only. It is meant for tests and for
+// trying the programs without touching a file.
+//
+// Contacts are copied, lists and maps included, on the way in and out, so
+// that changing a contact it returned or was given never changes the book
+// behind its back.
+type MemoryStore struct {
+	mu       sync.Mutex
+	contacts []Contact
+}
+
+// NewMemoryStore returns a store holding a copy of contacts. Contacts
+// without an ID are given one.
+func NewMemoryStore(contacts ...Contact) *MemoryStore {
+	s := &MemoryStore{contacts: cloneAll(contacts)}
+	assignIDs(s.contacts)
+	return s
+}
+
+func (s *MemoryStore) List() ([]Contact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return versioned(cloneAll(s.contacts)), nil
+}
+
+func (s *MemoryStore) Get(id string) (Contact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contact, err := get(s.contacts, id)
+	if err != nil {
+		return Contact{}, err
+	}
+	return withVersion(clone(contact)), nil
+}
+
+func (s *MemoryStore) Add(contact Contact) (Contact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contacts, contact, err := add(s.contacts, clone(contact))
+	if err != nil {
+		return Contact{}, err
+	}
+	s.contacts = contacts
+	return withVersion(clone(contact)), nil
+}
+
+func (s *MemoryStore) AddMany(added []Contact) ([]Contact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contacts, stored, err := addAll(s.contacts, cloneAll(added))
+	if err != nil {
+		return nil, err
+	}
+	s.contacts = contacts
+	return versioned(cloneAll(stored)), nil
+}
+
+func (s *MemoryStore) Update(contact Contact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contacts, err := replace(s.contacts, clone(contact))
+	if err != nil {
+		return err
+	}
+	s.contacts = contacts
+	return nil
+}
+
+func (s *MemoryStore) UpdateMany(changed []Contact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contacts, err := replaceAll(s.contacts, cloneAll(changed))
+	if err != nil {
+		return err
+	}
+	s.contacts = contacts
+	return nil
+}
+
+func (s *MemoryStore) Apply(changed, added []Contact) ([]Contact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contacts, stored, err := apply(s.contacts, cloneAll(changed), cloneAll(added))
+	if err != nil {
+		return nil, err
+	}
+	s.contacts = contacts
+	return versioned(cloneAll(stored)), nil
+}
+
+func (s *MemoryStore) Delete(contact Contact) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contacts, err := remove(s.contacts, contact)
+	if err != nil {
+		return err
+	}
+	s.contacts = contacts
+	return nil
+}
+
+func (s *MemoryStore) Query(q Query) ([]Contact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return versioned(cloneAll(filter(s.contacts, q))), nil
+}
+
+// clone returns a copy of c that shares no list or map with it.
+func clone(c Contact) Contact {
+	c.Emails = slices.Clone(c.Emails)
+	c.Phones = slices.Clone(c.Phones)
+	c.Addresses = slices.Clone(c.Addresses)
+	c.Tags = slices.Clone(c.Tags)
+	c.Events = slices.Clone(c.Events)
+	c.Interactions = slices.Clone(c.Interactions)
+	c.Fields = maps.Clone(c.Fields)
+	c.Modified = maps.Clone(c.Modified)
+	return c
+}
+
+// cloneAll returns copies of contacts made with clone.
+func cloneAll(contacts []Contact) []Contact {
+	copied := make([]Contact, len(contacts))
+	for i, c := range contacts {
+		copied[i] = clone(c)
+	}
+	return copied
+}
@@ -0,0 +1,47 @@
+package book
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMemoryStoreCopies(t *testing.T) {
+	s := NewMemoryStore(
+		Contact{Name: "Jane Doe", Tags: []string{"family", "friends"}, Emails: []Email{{Address: "jane@example.com", Primary: true}}},
+		Contact{Name: "John Roe", Tags: []string{"work"}},
+	)
+	before, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jane := before[0]
+	jane.RemoveTag("family")
+	jane.Emails[0].Primary = false
+	jane.Fields = map[string]string{"slack": "@jane"}
+	stored, err := s.Get(jane.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stored.Tags, []string{"family", "friends"}) || !stored.Emails[0].Primary {
+		t.Errorf("changing a listed contact changed the book: %+v", stored)
+	}
+
+	if err := s.Update(jane); err != nil {
+		t.Fatal(err)
+	}
+	jane.Tags[0] = "changed after the update"
+	if err := s.Delete(before[1]); err != nil {
+		t.Fatal(err)
+	}
+	if before[1].Name != "John Roe" || !reflect.DeepEqual(before[0].Tags, []string{"family", "friends"}) {
+		t.Errorf("changing the book changed an earlier list: %+v", before)
+	}
+	after, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 1 || !reflect.DeepEqual(after[0].Tags, []string{"friends"}) {
+		t.Errorf("book after the changes = %+v", after)
+	}
+}
@@ -0,0 +1,128 @@
+package book
+
+import "strings"
+
+// FindDuplicate returns the index of the first of contacts that c is
+// another copy of, or -1: the one with the ID of c, one sharing an email
+// address or a phone number with c or, when c has neither, one with the
+// same name.
+func FindDuplicate(contacts []Contact, c Contact) int {
+	if c.ID != "" {
+		if i := find(contacts, c.ID); i >= 0 {
+			return i
+		}
+	}
+	for i, other := range contacts {
+		for _, e := range c.Emails {
+			if other.hasEmail(e.Address) {
+				return i
+			}
+		}
+		for _, p := range c.Phones {
+			if other.hasPhone(p.Number) {
+				return i
+			}
+		}
+	}
+	if len(c.Emails) > 0 || len(c.Phones) > 0 || c.Name == "" {
+		return -1
+	}
+	for i, other := range contacts {
+		if strings.EqualFold(other.Name, c.Name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Merge adds to the contact what other knows and it lacks: the emails,
+// phones, addresses, events, tags and custom fields it does not have yet,
+// the nickname and work details it leaves empty, and notes it does not
+// already hold. Nothing the contact holds is overwritten, and its primary
+// email and phone stay the primary ones. Merge reports whether anything
+// was added.
+func (c *Contact) Merge(other Contact) bool {
+	changed := false
+	fill := func(field *string, value string) {
+		if *field == "" && value != "" {
+			*field = value
+			changed = true
+		}
+	}
+	// a contact saved before names were structured keeps its name as is
+	if !c.NameParts.IsZero() {
+		fill(&c.NameParts.Nickname, other.NameParts.Nickname)
+	}
+	for _, e := range other.Emails {
+		if !c.hasEmail(e.Address) {
+			e.Primary = false
+			c.Emails = append(c.Emails, e)
+			changed = true
+		}
+	}
+	for _, p := range other.Phones {
+		if !c.hasPhone(p.Number) {
+			p.Primary = false
+			c.Phones = append(c.Phones, p)
+			changed = true
+		}
+	}
+	for _, a := range other.Addresses {
+		known := false
+		for _, mine := range c.Addresses {
+			known = known || strings.EqualFold(mine.String(), a.String())
+		}
+		if !known {
+			c.Addresses = append(c.Addresses, a)
+			changed = true
+		}
+	}
+	fill(&c.Organization, other.Organization)
+	fill(&c.Department, other.Department)
+	fill(&c.Title, other.Title)
+	for _, e := range other.Events {
+		if _, ok := c.EventDate(e.Label); !ok {
+			c.SetEvent(e.Label, e.Date)
+			changed = true
+		}
+	}
+	for _, tag := range other.Tags {
+		changed = c.AddTag(tag) || changed
+	}
+	if other.Favorite && !c.Favorite {
+		c.Favorite = true
+		changed = true
+	}
+	if other.Notes != "" && !strings.Contains(c.Notes, other.Notes) {
+		c.Notes = NormalizeNotes(c.Notes + "\n" + other.Notes)
+		changed = true
+	}
+	for _, name := range FieldNames([]Contact{other}) {
+		if c.Field(name) == "" {
+			c.SetField(name, other.Field(name))
+			changed = true
+		}
+	}
+	if changed {
+		c.Normalize()
+	}
+	return changed
+}
+
+func (c Contact) hasEmail(address string) bool {
+	for _, e := range c.Emails {
+		if strings.EqualFold(e.Address, address) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Contact) hasPhone(number string) bool {
+	for _, p := range c.Phones {
+		if p.Number == number {
+			return true
+		}
+	}
+	return false
+}
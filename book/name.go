@@ -0,0 +1,270 @@
+package book
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// NameParts is the structured name of a contact. Family holds the whole
+// family name, particles such as "van der" or "al-" included.
+type NameParts struct {
+	Prefix   string `json:"prefix,omitempty"`
+	Given    string `json:"given,omitempty"`
+	Middle   string `json:"middle,omitempty"`
+	Family   string `json:"family,omitempty"`
+	Suffix   string `json:"suffix,omitempty"`
+	Nickname string `json:"nickname,omitempty"`
+}
+
+// NameOrder selects how structured names are displayed.
+type NameOrder int
+
+const (
+	// GivenFirst shows names as "Dr Jan van der Berg Jr".
+	GivenFirst NameOrder = iota
+	// FamilyFirst shows names as "van der Berg, Dr Jan, Jr".
+	FamilyFirst
+)
+
+// ParseNameOrder returns the order named "given" or "family".
+func ParseNameOrder(name string) (NameOrder, error) {
+	switch strings.ToLower(name) {
+	case "given", "":
+		return GivenFirst, nil
+	case "family":
+		return FamilyFirst, nil
+	}
+	return 0, fmt.Errorf("unknown name order %q (want given or family)", name)
+}
+
+// namePrefixes, nameSuffixes and nameParticles are compared in lower case
+// without a trailing dot.
+var (
+	namePrefixes = words("mr mrs ms miss mx dr prof sir dame lady lord rev fr sheikh sheikha sayed eng")
+	nameSuffixes = words("jr sr i ii iii iv v phd md dds esq mba cpa")
+	// particles that start a family name
+	nameParticles = words("van von der den de del della di da das dos du la le ter ten bin ibn bint abu al el")
+	// suffixes written in capitals, or in a mixed case of their own
+	suffixSpellings = map[string]string{"i": "I", "ii": "II", "iii": "III", "iv": "IV", "v": "V",
+		"phd": "PhD", "md": "MD", "dds": "DDS", "mba": "MBA", "cpa": "CPA"}
+)
+
+func words(list string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(list) {
+		set[w] = true
+	}
+	return set
+}
+
+// wordKey is the form of a word looked up in the tables above.
+func wordKey(word string) string {
+	return strings.TrimSuffix(strings.ToLower(word), ".")
+}
+
+// ParseName splits a full name into its parts. It understands
+// "Family, Given Middle" as well as "Given Middle Family", recognizes
+// prefixes such as "Dr", suffixes such as "Jr" or "III", keeps particles
+// such as "van der", "bin" or "al-" with the family name, and reads a
+// nickname written in quotes or parentheses: `Robert "Bob" Smith`.
+func ParseName(full string) NameParts {
+	var n NameParts
+	full, n.Nickname = cutNickname(full)
+
+	var family []string
+	if before, after, ok := strings.Cut(full, ","); ok {
+		// "Family, Given Middle" unless what follows the comma is a suffix
+		rest := strings.Fields(after)
+		if len(rest) > 0 && !allSuffixes(rest) {
+			family = strings.Fields(before)
+			full = after
+		} else {
+			full = before + " " + after
+		}
+	}
+	fields := strings.Fields(full)
+
+	for len(fields) > 0 && namePrefixes[wordKey(fields[0])] {
+		n.Prefix = strings.TrimSpace(n.Prefix + " " + fields[0])
+		fields = fields[1:]
+	}
+	for len(fields) > 1 && nameSuffixes[wordKey(fields[len(fields)-1])] {
+		n.Suffix = strings.TrimSpace(fields[len(fields)-1] + " " + n.Suffix)
+		fields = fields[:len(fields)-1]
+	}
+	if family == nil && len(fields) > 1 {
+		// the last word and the particles before it form the family name
+		start := len(fields) - 1
+		for start > 1 && isParticle(fields[start-1]) {
+			start--
+		}
+		family = fields[start:]
+		fields = fields[:start]
+	}
+	if len(fields) > 0 {
+		n.Given = fields[0]
+		n.Middle = strings.Join(fields[1:], " ")
+	}
+	n.Family = strings.Join(family, " ")
+	return n
+}
+
+// cutNickname removes a nickname written in quotes or parentheses.
+func cutNickname(full string) (string, string) {
+	for _, pair := range []string{`""`, "''", "()", "“”"} {
+		open, close := string([]rune(pair)[0]), string([]rune(pair)[1])
+		start := strings.Index(full, open)
+		if start < 0 {
+			continue
+		}
+		// a quote inside a word, as in O'Neill, is not a nickname
+		if start > 0 && !unicode.IsSpace(rune(full[start-1])) {
+			continue
+		}
+		end := strings.Index(full[start+len(open):], close)
+		if end < 0 {
+			continue
+		}
+		nickname := strings.TrimSpace(full[start+len(open) : start+len(open)+end])
+		return full[:start] + " " + full[start+len(open)+end+len(close):], nickname
+	}
+	return full, ""
+}
+
+func allSuffixes(fields []string) bool {
+	for _, f := range fields {
+		if !nameSuffixes[wordKey(f)] {
+			return false
+		}
+	}
+	return true
+}
+
+func isParticle(word string) bool {
+	key := wordKey(word)
+	if nameParticles[key] {
+		return true
+	}
+	// "al-", "el-" written on their own
+	return strings.HasSuffix(key, "-") && nameParticles[strings.TrimSuffix(key, "-")]
+}
+
+// IsZero reports whether no part of the name is filled in.
+func (n NameParts) IsZero() bool {
+	return n == NameParts{}
+}
+
+// Format renders the name in the given order. The nickname is left out.
+func (n NameParts) Format(order NameOrder) string {
+	given := joinWords(n.Prefix, n.Given, n.Middle)
+	if order == FamilyFirst && n.Family != "" {
+		s := n.Family
+		if given != "" {
+			s += ", " + given
+		}
+		if n.Suffix != "" {
+			s += ", " + n.Suffix
+		}
+		return s
+	}
+	return joinWords(given, n.Family, n.Suffix)
+}
+
+func joinWords(parts ...string) string {
+	return strings.Join(nonEmpty(parts...), " ")
+}
+
+// Capitalize applies CapitalizeName to every part of the name.
+func (n NameParts) Capitalize() NameParts {
+	return NameParts{
+		Prefix:   CapitalizeName(n.Prefix),
+		Given:    CapitalizeName(n.Given),
+		Middle:   CapitalizeName(n.Middle),
+		Family:   capitalizeInner(n.Family),
+		Suffix:   capitalizeInner(n.Suffix),
+		Nickname: CapitalizeName(n.Nickname),
+	}
+}
+
+// capitalizeInner capitalizes a family name or suffix on its own the way
+// it is capitalized inside a full name, so "van der berg" keeps its
+// particles in lower case and "iii" becomes "III".
+func capitalizeInner(part string) string {
+	if strings.TrimSpace(part) == "" {
+		return ""
+	}
+	first := "x "
+	if strings.ToUpper(part) == part {
+		first = "X "
+	}
+	return strings.TrimPrefix(CapitalizeName(first+part), "X ")
+}
+
+// SetName replaces the name of the contact with full, capitalized with
+// CapitalizeName and split with ParseName.
+func (c *Contact) SetName(full string) {
+	c.NameParts = ParseName(CapitalizeName(full))
+	c.Name = c.NameParts.Format(GivenFirst)
+	if c.Name == "" {
+		c.Name = CapitalizeName(full)
+	}
+}
+
+// SetNameParts replaces the name of the contact with the given parts,
+// capitalized with CapitalizeName.
+func (c *Contact) SetNameParts(n NameParts) {
+	c.NameParts = n.Capitalize()
+	c.Name = c.NameParts.Format(GivenFirst)
+}
+
+// DisplayName returns the name of the contact in the given order. Contacts
+// saved before names were structured show their name as it was typed.
+func (c Contact) DisplayName(order NameOrder) string {
+	if c.NameParts.IsZero() {
+		return c.Name
+	}
+	return c.NameParts.Format(order)
+}
+
+// parts returns the structured name, worked out from Name for contacts
+// saved before names were structured.
+func (c Contact) parts() NameParts {
+	if c.NameParts.IsZero() {
+		return ParseName(c.Name)
+	}
+	return c.NameParts
+}
+
+// SortByName sorts contacts by their name in the given order, so
+// FamilyFirst sorts by family name and then by given name. Case is ignored
+// and particles such as "van der" are skipped when comparing family names.
+func SortByName(contacts []Contact, order NameOrder) {
+	key := func(c Contact) string {
+		if order == FamilyFirst {
+			n := c.parts()
+			return strings.ToLower(sortableFamily(n.Family) + "\x00" + n.Given + "\x00" + n.Middle)
+		}
+		return strings.ToLower(c.DisplayName(GivenFirst))
+	}
+	sort.SliceStable(contacts, func(i, j int) bool {
+		return key(contacts[i]) < key(contacts[j])
+	})
+}
+
+// sortableFamily drops the particles a family name starts with, so that
+// "van der Berg" sorts under B.
+func sortableFamily(family string) string {
+	fields := strings.Fields(family)
+	for len(fields) > 1 && isParticle(fields[0]) {
+		fields = fields[1:]
+	}
+	s := strings.Join(fields, " ")
+	for _, particle := range []string{"al-", "el-"} {
+		if len(s) > len(particle) && strings.EqualFold(s[:len(particle)], particle) {
+			s = s[len(particle):]
+		}
+	}
+	return s
+}
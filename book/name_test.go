@@ -0,0 +1,88 @@
+package book
+
+import (
+	"testing"
+)
+
+func TestCapitalizeName(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"jane doe", "Jane Doe"},
+		{"  jane   doe ", "Jane Doe"},
+		{"JANE DOE", "Jane Doe"},
+		{"jane McDonald", "Jane McDonald"},
+		{"leonardo DiCaprio", "Leonardo DiCaprio"},
+		{"ronald mcdonald", "Ronald McDonald"},
+		{"shaquille o'neal", "Shaquille O'Neal"},
+		{"jean-luc picard", "Jean-Luc Picard"},
+		{"jan van der berg", "Jan van der Berg"},
+		{"van gogh", "Van Gogh"},
+		{"mohammed bin rashid", "Mohammed bin Rashid"},
+		{"omar al-farsi", "Omar al-Farsi"},
+		{"al-farsi omar", "Al-Farsi Omar"},
+		{"john smith iii", "John Smith III"},
+		{"jane doe phd", "Jane Doe PhD"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := CapitalizeName(tt.input); got != tt.want {
+			t.Errorf("CapitalizeName(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  NameParts
+	}{
+		{"Jane Doe", NameParts{Given: "Jane", Family: "Doe"}},
+		{"Jane", NameParts{Given: "Jane"}},
+		{"Jane Mary Ann Doe", NameParts{Given: "Jane", Middle: "Mary Ann", Family: "Doe"}},
+		{"Doe, Jane Mary", NameParts{Given: "Jane", Middle: "Mary", Family: "Doe"}},
+		{"Dr. Jane Doe", NameParts{Prefix: "Dr.", Given: "Jane", Family: "Doe"}},
+		{"Prof Dr Jane Doe", NameParts{Prefix: "Prof Dr", Given: "Jane", Family: "Doe"}},
+		{"John Smith Jr.", NameParts{Given: "John", Family: "Smith", Suffix: "Jr."}},
+		{"John Smith, Jr.", NameParts{Given: "John", Family: "Smith", Suffix: "Jr."}},
+		{"John Smith Jr. PhD", NameParts{Given: "John", Family: "Smith", Suffix: "Jr. PhD"}},
+		{"Jan van der Berg", NameParts{Given: "Jan", Family: "van der Berg"}},
+		{"Mohammed bin Rashid", NameParts{Given: "Mohammed", Family: "bin Rashid"}},
+		{"Omar al- Farsi", NameParts{Given: "Omar", Family: "al- Farsi"}},
+		{`Robert "Bob" Smith`, NameParts{Given: "Robert", Family: "Smith", Nickname: "Bob"}},
+		{"Robert (Bob) Smith", NameParts{Given: "Robert", Family: "Smith", Nickname: "Bob"}},
+		{"Conan O'Brien", NameParts{Given: "Conan", Family: "O'Brien"}},
+		{"", NameParts{}},
+	}
+	for _, tt := range tests {
+		if got := ParseName(tt.input); got != tt.want {
+			t.Errorf("ParseName(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNamePartsFormat(t *testing.T) {
+	n := NameParts{Prefix: "Dr", Given: "Jan", Family: "van der Berg", Suffix: "Jr", Nickname: "Janni"}
+	if got, want := n.Format(GivenFirst), "Dr Jan van der Berg Jr"; got != want {
+		t.Errorf("Format(GivenFirst) = %q, want %q", got, want)
+	}
+	if got, want := n.Format(FamilyFirst), "van der Berg, Dr Jan, Jr"; got != want {
+		t.Errorf("Format(FamilyFirst) = %q, want %q", got, want)
+	}
+	if got, want := (NameParts{Given: "Cher"}).Format(FamilyFirst), "Cher"; got != want {
+		t.Errorf("Format(FamilyFirst) of a given name only = %q, want %q", got, want)
+	}
+}
+
+func TestSetNameParts(t *testing.T) {
+	var c Contact
+	c.SetNameParts(NameParts{Given: "jan", Family: "van der berg", Suffix: "iii"})
+	want := NameParts{Given: "Jan", Family: "van der Berg", Suffix: "III"}
+	if c.NameParts != want || c.Name != "Jan van der Berg III" {
+		t.Errorf("SetNameParts gave %q, %+v, want %+v", c.Name, c.NameParts, want)
+	}
+	c.SetName("DOE, JANE")
+	if c.Name != "Jane Doe" || c.NameParts.Family != "Doe" {
+		t.Errorf("SetName gave %q, %+v", c.Name, c.NameParts)
+	}
+}
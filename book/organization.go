@@ -0,0 +1,39 @@
+package book
+
+import (
+	"sort"
+	"strings"
+)
+
+// OrganizationGroup holds the contacts working at one organization.
+type OrganizationGroup struct {
+	// Organization is empty for the contacts without one.
+	Organization string
+	Contacts     []Contact
+}
+
+// GroupByOrganization splits contacts by organization, ignoring case. The
+// groups are sorted by name with the contacts without an organization last;
+// within a group contacts keep their order.
+func GroupByOrganization(contacts []Contact) []OrganizationGroup {
+	var groups []OrganizationGroup
+	index := map[string]int{}
+	for _, c := range contacts {
+		key := strings.ToLower(strings.TrimSpace(c.Organization))
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, OrganizationGroup{Organization: strings.TrimSpace(c.Organization)})
+		}
+		groups[i].Contacts = append(groups[i].Contacts, c)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i].Organization, groups[j].Organization
+		if a == "" || b == "" {
+			return b == "" && a != ""
+		}
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+	return groups
+}
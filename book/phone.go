@@ -0,0 +1,294 @@
+package book
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidPhone is returned when a phone number cannot be parsed.
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// DefaultRegion is the region assumed for numbers typed without a country
+// code when the caller does not choose one.
+const DefaultRegion = "AE"
+
+// PhoneStyle selects how phone numbers are displayed.
+type PhoneStyle int
+
+const (
+	// International shows every number as "+971 50 123 4567".
+	International PhoneStyle = iota
+	// National shows numbers of the default region as "050 123 4567", or
+	// "(415) 555-0199" in North America, and every other number in
+	// international style.
+	National
+)
+
+// ParsePhoneStyle returns the style named "national" or "international".
+func ParsePhoneStyle(name string) (PhoneStyle, error) {
+	switch strings.ToLower(name) {
+	case "international", "":
+		return International, nil
+	case "national":
+		return National, nil
+	}
+	return 0, fmt.Errorf("unknown phone format %q (want national or international)", name)
+}
+
+// phoneRegion holds the numbering rules of one country.
+type phoneRegion struct {
+	code    string // ISO 3166 country code
+	calling string // country calling code
+	trunk   string // prefix dialled before national numbers, if any
+	lengths []int  // valid lengths of the national significant number
+	groups  map[int][]int
+}
+
+// phoneRegions lists the countries whose numbers are checked digit by digit.
+// Numbers of other countries are accepted when they fit E.164.
+var phoneRegions = []phoneRegion{
+	{"AE", "971", "0", []int{8, 9}, map[int][]int{8: {1, 3, 4}, 9: {2, 3, 4}}},
+	{"SA", "966", "0", []int{8, 9}, map[int][]int{8: {1, 3, 4}, 9: {2, 3, 4}}},
+	{"QA", "974", "", []int{8}, map[int][]int{8: {4, 4}}},
+	{"KW", "965", "", []int{8}, map[int][]int{8: {4, 4}}},
+	{"BH", "973", "", []int{8}, map[int][]int{8: {4, 4}}},
+	{"OM", "968", "", []int{8}, map[int][]int{8: {4, 4}}},
+	{"JO", "962", "0", []int{8, 9}, map[int][]int{8: {1, 3, 4}, 9: {1, 4, 4}}},
+	{"LB", "961", "0", []int{7, 8}, map[int][]int{7: {1, 3, 3}, 8: {2, 3, 3}}},
+	{"SY", "963", "0", []int{8, 9}, map[int][]int{8: {2, 3, 3}, 9: {3, 3, 3}}},
+	{"IQ", "964", "0", []int{8, 9, 10}, map[int][]int{10: {3, 3, 4}}},
+	{"EG", "20", "0", []int{8, 9, 10}, map[int][]int{9: {2, 3, 4}, 10: {3, 3, 4}}},
+	{"MA", "212", "0", []int{9}, map[int][]int{9: {3, 2, 2, 2}}},
+	{"TR", "90", "0", []int{10}, map[int][]int{10: {3, 3, 2, 2}}},
+	{"GB", "44", "0", []int{9, 10}, map[int][]int{9: {3, 3, 3}, 10: {2, 4, 4}}},
+	{"IE", "353", "0", []int{7, 8, 9}, map[int][]int{9: {2, 3, 4}}},
+	{"FR", "33", "0", []int{9}, map[int][]int{9: {1, 2, 2, 2, 2}}},
+	{"DE", "49", "0", []int{6, 7, 8, 9, 10, 11}, nil},
+	{"NL", "31", "0", []int{9}, map[int][]int{9: {1, 4, 4}}},
+	{"ES", "34", "", []int{9}, map[int][]int{9: {3, 3, 3}}},
+	{"US", "1", "1", []int{10}, map[int][]int{10: {3, 3, 4}}},
+	{"CA", "1", "1", []int{10}, map[int][]int{10: {3, 3, 4}}},
+	{"IN", "91", "0", []int{10}, map[int][]int{10: {5, 5}}},
+	{"PK", "92", "0", []int{9, 10}, map[int][]int{10: {3, 7}}},
+	{"PH", "63", "0", []int{8, 9, 10}, map[int][]int{10: {3, 3, 4}}},
+	{"CN", "86", "0", []int{10, 11}, map[int][]int{11: {3, 4, 4}}},
+	{"JP", "81", "0", []int{9, 10}, map[int][]int{10: {2, 4, 4}}},
+	{"SG", "65", "", []int{8}, map[int][]int{8: {4, 4}}},
+	{"AU", "61", "0", []int{9}, map[int][]int{9: {1, 4, 4}}},
+}
+
+// nationalFormats write the national numbers of the regions that do not
+// write them as the trunk prefix followed by the digit groups.
+var nationalFormats = map[string]func(string) string{"US": formatNANP, "CA": formatNANP}
+
+// canadianAreaCodes are the area codes of Canada. The other numbers of the
+// North American plan, which Canada shares with the United States, are
+// taken as numbers of the United States.
+var canadianAreaCodes = map[string]bool{
+	"204": true, "226": true, "236": true, "249": true, "250": true, "257": true, "263": true,
+	"289": true, "306": true, "343": true, "354": true, "365": true, "367": true, "368": true,
+	"382": true, "403": true, "416": true, "418": true, "428": true, "431": true, "437": true,
+	"438": true, "450": true, "460": true, "468": true, "474": true, "506": true, "514": true,
+	"519": true, "548": true, "579": true, "581": true, "584": true, "587": true, "600": true,
+	"604": true, "613": true, "639": true, "647": true, "672": true, "683": true, "705": true,
+	"709": true, "742": true, "753": true, "778": true, "780": true, "782": true, "807": true,
+	"819": true, "825": true, "867": true, "873": true, "879": true, "902": true, "905": true,
+}
+
+// lookupRegion returns the rules of the country with the given ISO code.
+func lookupRegion(code string) (phoneRegion, bool) {
+	code = strings.ToUpper(code)
+	for _, r := range phoneRegions {
+		if r.code == code {
+			return r, true
+		}
+	}
+	return phoneRegion{}, false
+}
+
+// ValidRegion reports whether code is a region ParsePhone knows.
+func ValidRegion(code string) bool {
+	_, ok := lookupRegion(code)
+	return ok
+}
+
+// Regions returns the ISO codes of the supported regions, sorted.
+func Regions() []string {
+	codes := make([]string, len(phoneRegions))
+	for i, r := range phoneRegions {
+		codes[i] = r.code
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ParsePhone validates a phone number and returns it in E.164 form, such as
+// "+971501234567". Numbers starting with "+" or "00" are international;
+// anything else is a national number of region. Spaces, dashes, dots and
+// parentheses are ignored.
+func ParsePhone(input, region string) (string, error) {
+	digits, international, err := phoneDigits(input)
+	if err != nil {
+		return "", err
+	}
+	if international {
+		return checkE164(digits)
+	}
+
+	r, ok := lookupRegion(region)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown region %q", ErrInvalidPhone, region)
+	}
+	national := digits
+	if stripped, ok := strings.CutPrefix(national, r.trunk); ok && r.trunk != "" && validLength(r, len(stripped)) {
+		national = stripped
+	}
+	if !validLength(r, len(national)) {
+		return "", fmt.Errorf("%w: %s numbers have %s digits after the %s", ErrInvalidPhone,
+			r.code, describeLengths(r.lengths), trunkName(r))
+	}
+	return "+" + r.calling + national, nil
+}
+
+// FormatPhone renders an E.164 number for display. Numbers that are not in
+// E.164 form, such as ones saved by older versions, are returned unchanged.
+func FormatPhone(e164 string, style PhoneStyle, region string) string {
+	if !strings.HasPrefix(e164, "+") {
+		return e164
+	}
+	digits := e164[1:]
+	r, national, ok := splitE164(digits)
+	if !ok {
+		return e164
+	}
+	groups := groupDigits(national, r.groups[len(national)])
+	if home, ok := lookupRegion(region); ok && style == National && home.calling == r.calling {
+		if format, ok := nationalFormats[r.code]; ok {
+			return format(national)
+		}
+		return r.trunk + groups
+	}
+	return "+" + r.calling + " " + groups
+}
+
+// phoneDigits strips the punctuation people type in phone numbers.
+func phoneDigits(input string) (digits string, international bool, err error) {
+	input = strings.TrimSpace(input)
+	switch {
+	case strings.HasPrefix(input, "+"):
+		international = true
+		input = input[1:]
+	case strings.HasPrefix(input, "00"):
+		international = true
+		input = input[2:]
+	}
+	var b strings.Builder
+	for _, ch := range input {
+		switch {
+		case ch >= '0' && ch <= '9':
+			b.WriteRune(ch)
+		case strings.ContainsRune(" -.()/", ch):
+		default:
+			return "", false, fmt.Errorf("%w: unexpected %q", ErrInvalidPhone, ch)
+		}
+	}
+	if b.Len() == 0 {
+		return "", false, fmt.Errorf("%w: no digits", ErrInvalidPhone)
+	}
+	return b.String(), international, nil
+}
+
+// checkE164 validates the digits of an international number.
+func checkE164(digits string) (string, error) {
+	if r, national, ok := splitE164(digits); ok {
+		if !validLength(r, len(national)) {
+			return "", fmt.Errorf("%w: %s numbers have %s digits after +%s", ErrInvalidPhone,
+				r.code, describeLengths(r.lengths), r.calling)
+		}
+		return "+" + digits, nil
+	}
+	if len(digits) < 8 || len(digits) > 15 || digits[0] == '0' {
+		return "", fmt.Errorf("%w: international numbers have 8 to 15 digits", ErrInvalidPhone)
+	}
+	return "+" + digits, nil
+}
+
+// splitE164 finds the region whose calling code starts digits. Calling
+// codes are prefix-free, so at most one code can match, but one code may
+// serve several regions: +1 is Canada for the Canadian area codes and the
+// United States otherwise.
+func splitE164(digits string) (phoneRegion, string, bool) {
+	for _, r := range phoneRegions {
+		national, ok := strings.CutPrefix(digits, r.calling)
+		if !ok {
+			continue
+		}
+		if r.code == "US" && len(national) >= 3 && canadianAreaCodes[national[:3]] {
+			r, _ = lookupRegion("CA")
+		}
+		return r, national, true
+	}
+	return phoneRegion{}, "", false
+}
+
+// formatNANP writes a number of the North American plan as
+// "(415) 555-0199".
+func formatNANP(national string) string {
+	if len(national) != 10 {
+		return national
+	}
+	return "(" + national[:3] + ") " + national[3:6] + "-" + national[6:]
+}
+
+func validLength(r phoneRegion, n int) bool {
+	for _, length := range r.lengths {
+		if length == n {
+			return true
+		}
+	}
+	return false
+}
+
+// groupDigits splits digits into space separated groups of the given sizes,
+// or into groups of at most four digits when no sizes are known.
+func groupDigits(digits string, sizes []int) string {
+	if sizes == nil {
+		for n := len(digits); n > 0; n -= 4 {
+			sizes = append(sizes, min(n, 4))
+		}
+		if len(sizes) > 1 && sizes[len(sizes)-1] < 3 {
+			sizes[len(sizes)-2] -= 3 - sizes[len(sizes)-1]
+			sizes[len(sizes)-1] = 3
+		}
+	}
+	var parts []string
+	for _, size := range sizes {
+		parts = append(parts, digits[:size])
+		digits = digits[size:]
+	}
+	return strings.Join(parts, " ")
+}
+
+func describeLengths(lengths []int) string {
+	if len(lengths) == 1 {
+		return fmt.Sprint(lengths[0])
+	}
+	first, last := lengths[0], lengths[len(lengths)-1]
+	if len(lengths) > 2 && last-first == len(lengths)-1 {
+		return fmt.Sprintf("%d to %d", first, last)
+	}
+	parts := make([]string, len(lengths))
+	for i, n := range lengths {
+		parts[i] = fmt.Sprint(n)
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " or " + parts[len(parts)-1]
+}
+
+func trunkName(r phoneRegion) string {
+	if r.trunk == "" {
+		return "country code"
+	}
+	return "leading " + r.trunk
+}
@@ -0,0 +1,100 @@
+package book
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePhone(t *testing.T) {
+	tests := []struct {
+		input, region, want string
+	}{
+		{"050 123 4567", "AE", "+971501234567"},
+		{"04 123 4567", "AE", "+97141234567"},
+		{"+971 50 123 4567", "US", "+971501234567"},
+		{"00971501234567", "GB", "+971501234567"},
+		{"(415) 555-0199", "US", "+14155550199"},
+		{"1-415-555-0199", "US", "+14155550199"},
+		{"416.555.0199", "CA", "+14165550199"},
+		{"07911 123456", "GB", "+447911123456"},
+		{"06 12 34 56 78", "FR", "+33612345678"},
+		{"5555 1234", "QA", "+97455551234"},
+		{"+49 30 1234567", "AE", "+49301234567"},
+		{"+852 2123 4567", "AE", "+85221234567"},
+	}
+	for _, tt := range tests {
+		got, err := ParsePhone(tt.input, tt.region)
+		if err != nil {
+			t.Errorf("ParsePhone(%q, %s): %v", tt.input, tt.region, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePhone(%q, %s) = %q, want %q", tt.input, tt.region, got, tt.want)
+		}
+	}
+
+	invalid := []struct {
+		input, region string
+	}{
+		{"", "AE"},
+		{"050 123", "AE"},
+		{"415 555 019", "US"},
+		{"+1 415 555 019", "AE"},
+		{"+971 50 123 45678", "AE"},
+		{"050-CALL-NOW", "AE"},
+		{"050 123 4567", "XX"},
+		{"+0123456789", "AE"},
+	}
+	for _, tt := range invalid {
+		if got, err := ParsePhone(tt.input, tt.region); !errors.Is(err, ErrInvalidPhone) {
+			t.Errorf("ParsePhone(%q, %s) = %q, %v, want ErrInvalidPhone", tt.input, tt.region, got, err)
+		}
+	}
+}
+
+func TestFormatPhone(t *testing.T) {
+	tests := []struct {
+		e164   string
+		style  PhoneStyle
+		region string
+		want   string
+	}{
+		{"+971501234567", International, "AE", "+971 50 123 4567"},
+		{"+971501234567", National, "AE", "050 123 4567"},
+		{"+971501234567", National, "US", "+971 50 123 4567"},
+		{"+14155550199", International, "US", "+1 415 555 0199"},
+		{"+14155550199", National, "US", "(415) 555-0199"},
+		{"+14165550199", National, "US", "(416) 555-0199"},
+		{"+14155550199", National, "CA", "(415) 555-0199"},
+		{"+14155550199", National, "AE", "+1 415 555 0199"},
+		{"+447911123456", National, "GB", "079 1112 3456"},
+		{"+33612345678", National, "FR", "06 12 34 56 78"},
+		{"+97455551234", National, "QA", "5555 1234"},
+		{"+49301234567", International, "DE", "+49 3012 34 567"},
+		{"+85221234567", National, "AE", "+85221234567"},
+		{"050 123 4567", National, "AE", "050 123 4567"},
+	}
+	for _, tt := range tests {
+		if got := FormatPhone(tt.e164, tt.style, tt.region); got != tt.want {
+			t.Errorf("FormatPhone(%q, %v, %s) = %q, want %q", tt.e164, tt.style, tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestSplitE164SharedCallingCode(t *testing.T) {
+	tests := []struct {
+		digits, region string
+	}{
+		{"14155550199", "US"},
+		{"14165550199", "CA"},
+		{"16045550199", "CA"},
+		{"12125550199", "US"},
+		{"971501234567", "AE"},
+	}
+	for _, tt := range tests {
+		r, _, ok := splitE164(tt.digits)
+		if !ok || r.code != tt.region {
+			t.Errorf("splitE164(%q) = %s, %v, want %s", tt.digits, r.code, ok, tt.region)
+		}
+	}
+}
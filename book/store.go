@@ -0,0 +1,282 @@
+package book
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no contact has the requested ID.
+var ErrNotFound = errors.New("contact not found")
+
+// ErrConflict is returned when a contact being updated or deleted was
+// changed or deleted by someone else since it was read, as told by its
+// Version. Reading the contact again and redoing the change resolves it.
+var ErrConflict = errors.New("contact changed since it was read; reload and try again")
+
+// Store is an address book backend. Contacts are identified by their ID.
+//
+// Every contact a store returns carries its Version. Update, UpdateMany and
+// Delete compare it with the stored contact and refuse with ErrConflict when
+// that changed since, so that a change made from a stale copy never
+// overwrites someone else's. Contacts without a Version, such as those
+// built by hand, are not checked.
+type Store interface {
+	// List returns every contact in storage order.
+	List() ([]Contact, error)
+	// Get returns the contact with the given ID.
+	Get(id string) (Contact, error)
+	// Add appends a new contact, assigning it an ID when it has none and
+	// stamping the time it was created, and returns the stored contact.
+	Add(contact Contact) (Contact, error)
+	// AddMany adds several contacts at once, as Add does, and returns them
+	// as stored. Either all of them are added or none is.
+	AddMany(contacts []Contact) ([]Contact, error)
+	// Update replaces the stored contact that has the same ID as contact,
+	// stamping the time of the change on the contact and on every field
+	// that differs.
+	Update(contact Contact) error
+	// UpdateMany replaces several contacts at once, as Update does. Either
+	// all of them are replaced or none is.
+	UpdateMany(contacts []Contact) error
+	// Apply replaces the contacts of changed as UpdateMany does and adds
+	// those of added as AddMany does, returning the added ones as stored.
+	// Either every change is made or none is.
+	Apply(changed, added []Contact) ([]Contact, error)
+	// Delete removes the stored contact that has the same ID as contact.
+	Delete(contact Contact) error
+	// Query returns the contacts matching q.
+	Query(q Query) ([]Contact, error)
+}
+
+// Query selects contacts. The zero Query matches every contact.
+type Query struct {
+	// Name matches contacts whose name, emails, phones or addresses
+	// contain it, as decided by Contact.Matches.
+	Name string
+	// Organization matches contacts whose organization contains it,
+	// ignoring case.
+	Organization string
+	// Tags matches contacts carrying every one of these tags, or any of
+	// them when AnyTag is set.
+	Tags   []string
+	AnyTag bool
+	// Since matches contacts changed at that time or later.
+	Since time.Time
+	// Favorites matches favorite contacts only.
+	Favorites bool
+}
+
+// Match reports whether contact satisfies every field of q.
+func (q Query) Match(contact Contact) bool {
+	if q.Organization != "" && !strings.Contains(strings.ToLower(contact.Organization), strings.ToLower(q.Organization)) {
+		return false
+	}
+	if len(q.Tags) > 0 && !q.matchTags(contact) {
+		return false
+	}
+	if !q.Since.IsZero() && contact.Updated.Before(q.Since) {
+		return false
+	}
+	if q.Favorites && !contact.Favorite {
+		return false
+	}
+	return q.Name == "" || contact.Matches(q.Name)
+}
+
+func (q Query) matchTags(contact Contact) bool {
+	for _, tag := range q.Tags {
+		if contact.HasTag(tag) == q.AnyTag {
+			return q.AnyTag
+		}
+	}
+	return !q.AnyTag
+}
+
+// Store kinds accepted by Open.
+const (
+	KindCSV    = "csv"
+	KindJSON   = "json"
+	KindMemory = "memory"
+)
+
+// DefaultPath returns the file used by a store kind when none is given.
+func DefaultPath(kind string) string {
+	if kind == KindJSON {
+		return "contacts.json"
+	}
+	return "contacts.txt"
+}
+
+// Open returns the store of the given kind backed by the file at path.
+// An empty path selects DefaultPath(kind); the memory store ignores it.
+func Open(kind, path string) (Store, error) {
+	if path == "" {
+		path = DefaultPath(kind)
+	}
+	switch strings.ToLower(kind) {
+	case KindCSV, "":
+		return NewCSVStore(path), nil
+	case KindJSON:
+		return NewJSONStore(path), nil
+	case KindMemory:
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown store %q (want csv, json or memory)", kind)
+}
+
+// find returns the index of the contact with the given ID, or -1.
+func find(contacts []Contact, id string) int {
+	for i, c := range contacts {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// get returns the contact with the given ID.
+func get(contacts []Contact, id string) (Contact, error) {
+	i := find(contacts, id)
+	if i < 0 {
+		return Contact{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return contacts[i], nil
+}
+
+// versioned returns a copy of contacts with the Version of each set.
+func versioned(contacts []Contact) []Contact {
+	copied := make([]Contact, len(contacts))
+	for i, c := range contacts {
+		copied[i] = withVersion(c)
+	}
+	return copied
+}
+
+// withVersion returns c with its Version set. The version is a hash of
+// everything saved about the contact, so it changes with any field.
+func withVersion(c Contact) Contact {
+	c.Version = ""
+	data, _ := json.Marshal(c) // a contact holds nothing JSON cannot encode
+	sum := sha256.Sum256(data)
+	c.Version = hex.EncodeToString(sum[:16])
+	return c
+}
+
+// locate returns the index of the stored contact that has the ID of
+// contact, refusing with ErrConflict when it is no longer the version
+// contact was read from.
+func locate(contacts []Contact, contact Contact) (int, error) {
+	i := find(contacts, contact.ID)
+	switch {
+	case i < 0 && contact.Version != "":
+		return -1, ErrConflict
+	case i < 0:
+		return -1, fmt.Errorf("%w: %s", ErrNotFound, contact.ID)
+	case contact.Version != "" && withVersion(contacts[i]).Version != contact.Version:
+		return -1, ErrConflict
+	}
+	return i, nil
+}
+
+// add appends contact after giving it an ID if it has none.
+func add(contacts []Contact, contact Contact) ([]Contact, Contact, error) {
+	contact.Version = ""
+	if contact.ID == "" {
+		contact.ID = NewID()
+	}
+	if find(contacts, contact.ID) >= 0 {
+		return nil, Contact{}, fmt.Errorf("duplicate contact id %s", contact.ID)
+	}
+	contact = created(contact)
+	return append(contacts, contact), contact, nil
+}
+
+// addAll appends every contact of added as add does. Nothing is added when
+// one of them cannot be.
+func addAll(contacts []Contact, added []Contact) ([]Contact, []Contact, error) {
+	all := append([]Contact(nil), contacts...)
+	stored := make([]Contact, len(added))
+	for i, contact := range added {
+		var err error
+		if all, stored[i], err = add(all, contact); err != nil {
+			return nil, nil, err
+		}
+	}
+	return all, stored, nil
+}
+
+// apply replaces the contacts of changed as replaceAll does, then adds the
+// contacts of added as addAll does. contacts is left as it was when one of
+// them fails.
+func apply(contacts, changed, added []Contact) ([]Contact, []Contact, error) {
+	contacts, err := replaceAll(append([]Contact(nil), contacts...), changed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return addAll(contacts, added)
+}
+
+// filter returns the contacts matching q.
+func filter(contacts []Contact, q Query) []Contact {
+	var matches []Contact
+	for _, c := range contacts {
+		if q.Match(c) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// replace swaps the contact with the same ID for contact.
+func replace(contacts []Contact, contact Contact) ([]Contact, error) {
+	i, err := locate(contacts, contact)
+	if err != nil {
+		return nil, err
+	}
+	contact.Version = ""
+	contacts[i] = updated(contacts[i], contact)
+	return contacts, nil
+}
+
+// replaceAll swaps every contact of changed for the stored one with the same
+// ID. Nothing is changed when one of them is missing or stale.
+func replaceAll(contacts []Contact, changed []Contact) ([]Contact, error) {
+	for _, contact := range changed {
+		if _, err := locate(contacts, contact); err != nil {
+			return nil, err
+		}
+	}
+	for _, contact := range changed {
+		i := find(contacts, contact.ID)
+		contact.Version = ""
+		contacts[i] = updated(contacts[i], contact)
+	}
+	return contacts, nil
+}
+
+// remove drops the stored contact with the ID of contact and clears the
+// references other contacts hold to it.
+func remove(contacts []Contact, contact Contact) ([]Contact, error) {
+	i, err := locate(contacts, contact)
+	if err != nil {
+		return nil, err
+	}
+	id := contact.ID
+	contacts = slices.Concat(contacts[:i], contacts[i+1:])
+	for j, old := range contacts {
+		if old.ManagerID == id {
+			contacts[j].ManagerID = ""
+		}
+		if old.AssistantID == id {
+			contacts[j].AssistantID = ""
+		}
+		contacts[j] = updated(old, contacts[j])
+	}
+	return contacts, nil
+}
@@ -0,0 +1,114 @@
+package book
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidTag is returned for a tag that cannot be stored.
+var ErrInvalidTag = errors.New("invalid tag")
+
+// NormalizeTag trims tag and collapses its inner spaces. Tags are compared
+// ignoring case but keep the case they were first typed in. They cannot be
+// empty or contain ";" or ",", which separate tags in lists.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(tag), " ")
+	if tag == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalidTag)
+	}
+	if strings.ContainsAny(tag, ";,") {
+		return "", fmt.Errorf("%w: %q contains ; or ,", ErrInvalidTag, tag)
+	}
+	return tag, nil
+}
+
+// HasTag reports whether the contact carries tag, ignoring case.
+func (c Contact) HasTag(tag string) bool {
+	return indexTag(c.Tags, tag) >= 0
+}
+
+// AddTag gives the contact tag unless it already has it, and reports
+// whether it was added.
+func (c *Contact) AddTag(tag string) bool {
+	if c.HasTag(tag) {
+		return false
+	}
+	c.Tags = append(c.Tags, tag)
+	return true
+}
+
+// RemoveTag takes tag away from the contact and reports whether it had it.
+func (c *Contact) RemoveTag(tag string) bool {
+	i := indexTag(c.Tags, tag)
+	if i < 0 {
+		return false
+	}
+	c.Tags = slices.Concat(c.Tags[:i], c.Tags[i+1:])
+	return true
+}
+
+// RenameTag replaces tag old with tag new and reports whether the contact
+// had old. A contact already carrying new keeps it once.
+func (c *Contact) RenameTag(old, new string) bool {
+	if !c.RemoveTag(old) {
+		return false
+	}
+	c.AddTag(new)
+	return true
+}
+
+// JoinTags renders tags as one semicolon separated list.
+func JoinTags(tags []string) string {
+	return strings.Join(tags, "; ")
+}
+
+// TagCount is a tag and the number of contacts carrying it.
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// Tags returns every tag used by contacts with the number of contacts
+// carrying it, sorted by tag ignoring case. These are the groups of the
+// book: a group is the set of contacts sharing a tag, and it exists as long
+// as one contact carries the tag.
+func Tags(contacts []Contact) []TagCount {
+	var counts []TagCount
+	index := map[string]int{}
+	for _, c := range contacts {
+		for _, tag := range c.Tags {
+			key := strings.ToLower(tag)
+			i, ok := index[key]
+			if !ok {
+				i = len(counts)
+				index[key] = i
+				counts = append(counts, TagCount{Tag: tag})
+			}
+			counts[i].Count++
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return strings.ToLower(counts[i].Tag) < strings.ToLower(counts[j].Tag)
+	})
+	return counts
+}
+
+func indexTag(tags []string, tag string) int {
+	for i, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return i
+		}
+	}
+	return -1
+}
+
+// FavoritesFirst moves the favorite contacts to the front, keeping the
+// order of the favorites and of the others.
+func FavoritesFirst(contacts []Contact) {
+	sort.SliceStable(contacts, func(i, j int) bool {
+		return contacts[i].Favorite && !contacts[j].Favorite
+	})
+}
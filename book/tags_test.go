@@ -0,0 +1,23 @@
+package book
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemoveTagLeavesCopies(t *testing.T) {
+	c := Contact{Name: "Jane Doe", Tags: []string{"family", "friends", "work"}}
+	copied := c
+	if !c.RemoveTag("FRIENDS") {
+		t.Fatal("RemoveTag did not find friends")
+	}
+	if want := []string{"family", "work"}; !reflect.DeepEqual(c.Tags, want) {
+		t.Errorf("tags = %v, want %v", c.Tags, want)
+	}
+	if want := []string{"family", "friends", "work"}; !reflect.DeepEqual(copied.Tags, want) {
+		t.Errorf("tags of the copy = %v, want %v", copied.Tags, want)
+	}
+	if c.RemoveTag("missing") {
+		t.Error("RemoveTag removed a tag the contact does not have")
+	}
+}
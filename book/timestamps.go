@@ -0,0 +1,138 @@
+package book
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// now is the clock stamping changes.
+var now = time.Now
+
+// trackedFields are the parts of a contact whose last change is recorded in
+// Contact.Modified, by name. Custom fields are tracked one by one as
+// "fields.NAME".
+var trackedFields = []struct {
+	name string
+	get  func(Contact) string
+}{
+	{"name", func(c Contact) string { return c.Name + "\x00" + fmt.Sprint(c.NameParts) }},
+	{"emails", func(c Contact) string { return JoinEmails(c.Emails) }},
+	{"phones", func(c Contact) string { return JoinPhones(c.Phones) }},
+	{"addresses", func(c Contact) string { return JoinAddresses(c.Addresses) }},
+	{"organization", func(c Contact) string { return c.Organization }},
+	{"department", func(c Contact) string { return c.Department }},
+	{"title", func(c Contact) string { return c.Title }},
+	{"manager", func(c Contact) string { return c.ManagerID }},
+	{"assistant", func(c Contact) string { return c.AssistantID }},
+	{"tags", func(c Contact) string { return JoinTags(c.Tags) }},
+	{"favorite", func(c Contact) string { return fmt.Sprint(c.Favorite) }},
+	{"events", func(c Contact) string { return JoinEvents(c.Events) }},
+	{"notes", func(c Contact) string { return c.Notes }},
+	{"interactions", func(c Contact) string { return JoinInteractions(c.Interactions) }},
+}
+
+// created stamps a contact being added. Created and Updated are kept when
+// they are already set, as for contacts copied from another book.
+func created(c Contact) Contact {
+	at := now().UTC().Truncate(time.Second)
+	if c.Created.IsZero() {
+		c.Created = at
+	}
+	if c.Updated.IsZero() {
+		c.Updated = c.Created
+	}
+	return c
+}
+
+// updated returns c, the new version of old, with the time stamps of old
+// and the current time recorded for every field that differs. Callers
+// cannot change the stamps themselves.
+func updated(old, c Contact) Contact {
+	c.Created, c.Updated, c.Modified = old.Created, old.Updated, nil
+	for name, at := range old.Modified {
+		c.stampField(name, at)
+	}
+	at := now().UTC().Truncate(time.Second)
+	changed := false
+	for _, field := range trackedFields {
+		if field.get(old) != field.get(c) {
+			c.stampField(field.name, at)
+			changed = true
+		}
+	}
+	for _, name := range FieldNames([]Contact{old, c}) {
+		if old.Field(name) != c.Field(name) {
+			c.stampField("fields."+name, at)
+			changed = true
+		}
+	}
+	if changed {
+		c.Updated = at
+	}
+	return c
+}
+
+func (c *Contact) stampField(name string, at time.Time) {
+	if c.Modified == nil {
+		c.Modified = map[string]time.Time{}
+	}
+	c.Modified[name] = at
+}
+
+// FieldChange is the last time one field of a contact changed.
+type FieldChange struct {
+	Field string
+	At    time.Time
+}
+
+// Changes returns the last change of every field of the contact, the most
+// recent first.
+func (c Contact) Changes() []FieldChange {
+	var changes []FieldChange
+	for name, at := range c.Modified {
+		changes = append(changes, FieldChange{name, at})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].At.Equal(changes[j].At) {
+			return changes[i].At.After(changes[j].At)
+		}
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// JoinChanges renders the changes as one semicolon separated list of
+// "field=time" entries.
+func JoinChanges(changes []FieldChange) string {
+	parts := make([]string, len(changes))
+	for i, change := range changes {
+		parts[i] = change.Field + "=" + change.At.UTC().Format(time.RFC3339)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// SortByUpdated sorts contacts by the time they were last changed, the
+// most recent first. Contacts saved before changes were recorded come
+// last, in their current order.
+func SortByUpdated(contacts []Contact) {
+	sort.SliceStable(contacts, func(i, j int) bool {
+		return contacts[i].Updated.After(contacts[j].Updated)
+	})
+}
+
+// formatStamp renders a time stamp stored in a CSV cell.
+func formatStamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// parseStamp reads a time stamp stored in a CSV cell, the zero time when it
+// does not parse.
+func parseStamp(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	return t
+}
@@ -0,0 +1,74 @@
+package book
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// IsValidEmail reports whether email looks like a valid address.
+func IsValidEmail(email string) bool {
+	return emailPattern.MatchString(email)
+}
+
+// lowerParticles stay in lower case inside a name: "Jan van der Berg",
+// "Mohammed bin Rashid".
+var lowerParticles = words("van von der den de del della di da das dos du la le ter ten bin ibn bint")
+
+// CapitalizeName tidies the spacing of name and fixes the case of the words
+// typed all in lower case, or of every word when the whole name is in upper
+// case. Words already in mixed case, such as "McDonald" or "DiCaprio", are
+// kept as typed. The others are title cased with the usual exceptions:
+// "McDonald", "O'Neill", "Jean-Luc", particles such as "van der" or "bin"
+// and an "al-" prefix in lower case after the first word, and suffixes
+// such as "III" or "PhD".
+func CapitalizeName(name string) string {
+	fields := strings.Fields(name)
+	shouting := strings.ToUpper(name) == name
+	for i, word := range fields {
+		if shouting || strings.ToLower(word) == word {
+			fields[i] = capitalizeWord(strings.ToLower(word), i == 0)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// capitalizeWord fixes the case of one lower case word of a name.
+func capitalizeWord(word string, first bool) string {
+	key := wordKey(word)
+	if !first && lowerParticles[key] {
+		return word
+	}
+	if spelling, ok := suffixSpellings[key]; ok && !first {
+		return spelling + strings.TrimPrefix(word, key)
+	}
+	parts := strings.Split(word, "-")
+	for i, part := range parts {
+		// "al-" and "el-" before a family name
+		if i == 0 && len(parts) > 1 && !first && (part == "al" || part == "el") {
+			continue
+		}
+		parts[i] = capitalizeSegment(part)
+	}
+	return strings.Join(parts, "-")
+}
+
+// capitalizeSegment title cases a word without hyphens, minding "Mc" and
+// single letter prefixes before an apostrophe, as in "O'Neill" or "D'Angelo".
+func capitalizeSegment(segment string) string {
+	title := cases.Title(language.English)
+	for _, apostrophe := range []string{"'", "’"} {
+		if before, after, ok := strings.Cut(segment, apostrophe); ok && len([]rune(before)) == 1 && after != "" {
+			return strings.ToUpper(before) + apostrophe + capitalizeSegment(after)
+		}
+	}
+	if rest, ok := strings.CutPrefix(segment, "mc"); ok && rest != "" && unicode.IsLetter([]rune(rest)[0]) {
+		return "Mc" + title.String(rest)
+	}
+	return title.String(segment)
+}
@@ -0,0 +1,584 @@
+package book
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// vcardProperty is one content line of a vCard, "[group.]NAME;PARAMS:VALUE",
+// with the group dropped, the name and parameter names in upper case, and
+// the value as written: still encoded and escaped.
+type vcardProperty struct {
+	name   string
+	params map[string][]string
+	value  string
+}
+
+// vcard is the properties of one card and the line it starts on. err is
+// the first error found while reading it.
+type vcard struct {
+	line  int
+	props []vcardProperty
+	err   error
+}
+
+// vcardImported are the properties mapped onto a contact, in lower case.
+// The others are skipped without being decoded.
+var vcardImported = words("version fn n nickname email tel adr org title bday anniversary note categories")
+
+// vcardLabels maps the TYPE parameters of emails, phones and addresses
+// onto labels when they differ. Other types are labels of their own,
+// except the vcardKinds.
+var vcardLabels = map[string]string{"cell": LabelMobile, "iphone": LabelMobile}
+
+// vcardKinds are the types telling what an entry is rather than whose it
+// is, which are not labels.
+var vcardKinds = words("pref voice text msg video textphone internet x400 dom intl postal parcel bbs modem car isdn pcs")
+
+// ReadVCards reads the vCards of r, in version 3.0 or 4.0 (RFC 2426 and
+// RFC 6350) as well as the older 2.1, and maps them onto contacts. Folded
+// lines are unfolded, quoted-printable values decoded and values in
+// another CHARSET converted to UTF-8. FN and N give the name, EMAIL, TEL
+// and ADR the emails, phones and addresses, labeled from their TYPE, ORG
+// and TITLE the work details, BDAY and ANNIVERSARY the events, NOTE the
+// notes and CATEGORIES the tags; other properties are ignored.
+//
+// Emails and phones are validated as NewContact does, with numbers lacking
+// a country code read as numbers of region. A card with an invalid value
+// or without a name is returned as rejected, with the reason. The error
+// reports a file that cannot be read or holds no card at all.
+func ReadVCards(r io.Reader, region string) ([]Contact, []Rejected, error) {
+	lines, err := readVCardLines(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var contacts []Contact
+	var rejected []Rejected
+	finish := func(card *vcard) {
+		contact, err := card.contact(region)
+		if card.err != nil {
+			err = card.err
+		}
+		if err != nil {
+			rejected = append(rejected, Rejected{Line: card.line, Name: contact.Name, Reason: err})
+			return
+		}
+		contacts = append(contacts, contact)
+	}
+	var card *vcard
+	found := false
+	for i := 0; i < len(lines); i++ {
+		start := i
+		text := lines[i]
+		for i+1 < len(lines) && folded(lines[i+1]) {
+			i++
+			text += lines[i][1:]
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		p, err := parseVCardLine(text)
+		if err == nil && strings.EqualFold(p.param("ENCODING"), "QUOTED-PRINTABLE") {
+			// soft line breaks of quoted-printable values are not folded
+			for strings.HasSuffix(p.value, "=") && i+1 < len(lines) {
+				i++
+				p.value += "\n" + lines[i]
+			}
+		}
+		switch {
+		case err == nil && p.name == "BEGIN" && strings.EqualFold(strings.TrimSpace(p.value), "VCARD"):
+			if card != nil {
+				card.fail(errors.New("card has no END:VCARD"))
+				finish(card)
+			}
+			card = &vcard{line: start + 1}
+			found = true
+		case card == nil:
+			// anything outside a card is ignored
+		case err != nil:
+			card.fail(fmt.Errorf("line %d: %w", start+1, err))
+		case p.name == "END" && strings.EqualFold(strings.TrimSpace(p.value), "VCARD"):
+			finish(card)
+			card = nil
+		default:
+			card.props = append(card.props, p)
+		}
+	}
+	if card != nil {
+		card.fail(errors.New("card has no END:VCARD"))
+		finish(card)
+	}
+	if !found {
+		return nil, nil, errors.New("no vCard found, cards start with BEGIN:VCARD")
+	}
+	return contacts, rejected, nil
+}
+
+// readVCardLines returns the lines of r without their line breaks or a
+// byte order mark.
+func readVCardLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	// photos are often written on one long line
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) > 0 {
+		lines[0] = strings.TrimPrefix(lines[0], "\ufeff")
+	}
+	return lines, nil
+}
+
+// folded reports whether line continues the one before it.
+func folded(line string) bool {
+	return line != "" && (line[0] == ' ' || line[0] == '\t')
+}
+
+// parseVCardLine splits an unfolded content line into its parts.
+func parseVCardLine(text string) (vcardProperty, error) {
+	malformed := errors.New("malformed line, want NAME:VALUE")
+	// the value starts after the first colon outside a quoted parameter
+	colon := -1
+	quoted := false
+	for i := 0; i < len(text) && colon < 0; i++ {
+		switch text[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return vcardProperty{}, malformed
+	}
+	head := splitQuoted(text[:colon], ';')
+	name := strings.ToUpper(strings.TrimSpace(head[0]))
+	if _, after, ok := strings.Cut(name, "."); ok {
+		name = after
+	}
+	if name == "" {
+		return vcardProperty{}, malformed
+	}
+	p := vcardProperty{name: name, params: map[string][]string{}, value: text[colon+1:]}
+	for _, param := range head[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		if !ok {
+			// vCard 2.1 gives types and encodings without a name
+			key, value = "TYPE", key
+			switch strings.ToUpper(value) {
+			case "QUOTED-PRINTABLE", "BASE64", "8BIT", "7BIT":
+				key = "ENCODING"
+			}
+		}
+		for _, v := range splitQuoted(value, ',') {
+			p.params[key] = append(p.params[key], strings.Trim(strings.TrimSpace(v), `"`))
+		}
+	}
+	return p, nil
+}
+
+// splitQuoted splits s on the separators outside double quotes.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// param returns the first value of the parameter called name.
+func (p vcardProperty) param(name string) string {
+	if values := p.params[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// types returns the TYPE parameters of the property in lower case. 4.0
+// quotes lists such as TYPE="work,voice", which are split here.
+func (p vcardProperty) types() []string {
+	var types []string
+	for _, value := range p.params["TYPE"] {
+		for _, t := range strings.Split(value, ",") {
+			types = append(types, strings.ToLower(strings.TrimSpace(t)))
+		}
+	}
+	return types
+}
+
+// label picks the label of an email, phone or address from its types,
+// mobile over any other.
+func (p vcardProperty) label() string {
+	label := ""
+	for _, t := range p.types() {
+		l, ok := vcardLabels[t]
+		if !ok {
+			l = t
+		}
+		if vcardKinds[l] || !validLabel(l) {
+			continue
+		}
+		if label == "" || l == LabelMobile {
+			label = l
+		}
+	}
+	return label
+}
+
+// preferred reports whether the property is marked as the preferred one,
+// with TYPE=pref in 3.0 or PREF=1 in 4.0.
+func (p vcardProperty) preferred() bool {
+	for _, t := range p.types() {
+		if t == "pref" {
+			return true
+		}
+	}
+	return p.param("PREF") == "1"
+}
+
+// decoded returns the value of the property decoded from its ENCODING and
+// CHARSET, still escaped.
+func (p vcardProperty) decoded() (string, error) {
+	value := p.value
+	if strings.EqualFold(p.param("ENCODING"), "QUOTED-PRINTABLE") {
+		b, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(value)))
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", p.name, err)
+		}
+		value = string(b)
+	}
+	if charset := p.param("CHARSET"); charset != "" && !strings.EqualFold(charset, "UTF-8") {
+		enc, err := ianaindex.IANA.Encoding(charset)
+		if err != nil || enc == nil {
+			return "", fmt.Errorf("%s: unknown charset %q", p.name, charset)
+		}
+		if value, err = enc.NewDecoder().String(value); err != nil {
+			return "", fmt.Errorf("%s: %w", p.name, err)
+		}
+	}
+	if !utf8.ValidString(value) {
+		return "", fmt.Errorf("%s: text is not UTF-8 and no CHARSET tells what it is", p.name)
+	}
+	return value, nil
+}
+
+// fail records the first error found in the card.
+func (card *vcard) fail(err error) {
+	if card.err == nil {
+		card.err = err
+	}
+}
+
+// contact maps the properties of the card onto a contact. The contact
+// carries the name of the card even when an error is returned.
+func (card *vcard) contact(region string) (Contact, error) {
+	var c Contact
+	var fn, nickname string
+	var n NameParts
+	var notes []string
+	var first error
+	fail := func(err error) {
+		if first == nil {
+			first = err
+		}
+	}
+	for _, p := range card.props {
+		if !vcardImported[strings.ToLower(p.name)] {
+			continue
+		}
+		value, err := p.decoded()
+		if err != nil {
+			fail(err)
+			continue
+		}
+		switch p.name {
+		case "VERSION":
+			if v := strings.TrimSpace(value); v != "2.1" && v != "3.0" && v != "4.0" {
+				fail(fmt.Errorf("unsupported vCard version %q", v))
+			}
+		case "FN":
+			fn = vcardText(value)
+		case "N":
+			f := vcardFields(value, 5, " ")
+			n = NameParts{Family: f[0], Given: f[1], Middle: f[2], Prefix: f[3], Suffix: f[4]}
+		case "NICKNAME":
+			nickname = vcardFields(value, 1, ", ")[0]
+		case "EMAIL":
+			address := strings.TrimPrefix(vcardText(value), "mailto:")
+			if !IsValidEmail(address) {
+				fail(fmt.Errorf("%w: %q", ErrInvalidEmail, address))
+				continue
+			}
+			c.Emails = append(c.Emails, Email{Label: p.label(), Address: address, Primary: p.preferred()})
+		case "TEL":
+			number := strings.TrimPrefix(vcardText(value), "tel:")
+			e164, err := ParsePhone(number, region)
+			if err != nil {
+				fail(fmt.Errorf("phone %q: %w", number, err))
+				continue
+			}
+			c.Phones = append(c.Phones, Phone{Label: p.label(), Number: e164, Primary: p.preferred()})
+		case "ADR":
+			// post office box, extended address, street, locality, region,
+			// postal code and country
+			f := vcardFields(value, 7, "\n")
+			a, err := NewAddress(p.label(), strings.Join(nonEmpty(f[0], f[1], f[2]), "\n"), f[3], f[4], f[5], f[6])
+			if errors.Is(err, ErrEmptyAddress) {
+				continue
+			}
+			if err != nil {
+				fail(err)
+				continue
+			}
+			c.Addresses = append(c.Addresses, a)
+		case "ORG":
+			// the organization and its units
+			f := vcardFields(value, 2, ", ")
+			c.Organization = f[0]
+			c.Department = strings.Join(nonEmpty(f[1:]...), ", ")
+		case "TITLE":
+			c.Title = vcardText(value)
+		case "BDAY", "ANNIVERSARY":
+			if strings.EqualFold(p.param("VALUE"), "text") {
+				continue
+			}
+			date, err := parseVCardDate(value)
+			if err != nil {
+				fail(fmt.Errorf("%s %q: %w", p.name, strings.TrimSpace(value), err))
+				continue
+			}
+			// Apple writes birthdays without a year in a placeholder one
+			if omit := p.param("X-APPLE-OMIT-YEAR"); omit != "" && omit == strconv.Itoa(date.Year) {
+				date.Year = 0
+			}
+			label := EventBirthday
+			if p.name == "ANNIVERSARY" {
+				label = EventAnniversary
+			}
+			c.SetEvent(label, date)
+		case "NOTE":
+			notes = append(notes, vcardText(value))
+		case "CATEGORIES":
+			for _, category := range splitEscaped(value, ',') {
+				if tag, err := NormalizeTag(vcardText(category)); err == nil {
+					c.AddTag(tag)
+				}
+			}
+		}
+	}
+	if n != (NameParts{}) {
+		c.SetNameParts(n)
+	} else {
+		c.SetName(fn)
+	}
+	if nickname != "" && c.Name != "" {
+		c.NameParts.Nickname = nickname
+	}
+	c.Notes = NormalizeNotes(strings.Join(notes, "\n"))
+	c.Normalize()
+	if first == nil && c.Name == "" {
+		first = ErrEmptyName
+	}
+	return c, first
+}
+
+// parseVCardDate reads the dates of BDAY and ANNIVERSARY: 19900517 or
+// 1990-05-17, and --0517 or --05-17 without the year, possibly followed by
+// a time.
+func parseVCardDate(value string) (Date, error) {
+	s := strings.TrimSpace(value)
+	if before, _, ok := strings.Cut(s, "T"); ok {
+		s = before
+	}
+	rest, noYear := strings.CutPrefix(s, "--")
+	digits := strings.ReplaceAll(rest, "-", "")
+	switch {
+	case noYear && len(digits) == 4:
+		return ParseDate("--" + digits[:2] + "-" + digits[2:])
+	case !noYear && len(digits) == 8:
+		return ParseDate(digits[:4] + "-" + digits[4:6] + "-" + digits[6:])
+	}
+	return Date{}, ErrInvalidDate
+}
+
+// vcardText unescapes a text value and trims it.
+func vcardText(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+			if value[i] == 'n' || value[i] == 'N' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(value[i])
+			}
+			continue
+		}
+		b.WriteByte(value[i])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// vcardFields splits a structured value such as N or ADR into at least n
+// unescaped fields. The values of a field holding a list are joined with
+// sep.
+func vcardFields(value string, n int, sep string) []string {
+	fields := splitEscaped(value, ';')
+	for i, field := range fields {
+		values := splitEscaped(field, ',')
+		for j := range values {
+			values[j] = vcardText(values[j])
+		}
+		fields[i] = strings.Join(nonEmpty(values...), sep)
+	}
+	for len(fields) < n {
+		fields = append(fields, "")
+	}
+	return fields
+}
+
+// splitEscaped splits s on the separators not escaped with a backslash,
+// leaving the escapes in place.
+func splitEscaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// WriteVCards writes contacts to w as vCard 4.0 (RFC 6350), one card per
+// contact, with CRLF line ends and lines folded at 75 octets. Emails,
+// phones and addresses carry their label as TYPE, "mobile" becoming
+// "cell", and PREF=1 marks the primary ones when there are several. The
+// birthday and anniversary are written as BDAY and ANNIVERSARY and the
+// tags as CATEGORIES; other events, favorites, the interaction log and
+// custom fields have no vCard property and are left out. ReadVCards reads
+// the cards back.
+func WriteVCards(w io.Writer, contacts []Contact) error {
+	bw := bufio.NewWriter(w)
+	line := func(format string, a ...any) {
+		writeContentLine(bw, fmt.Sprintf(format, a...))
+	}
+	for _, c := range contacts {
+		line("BEGIN:VCARD")
+		line("VERSION:4.0")
+		line("PRODID:-//contact-book//Contacts//EN")
+		if c.ID != "" {
+			line("UID:urn:uuid:%s", c.ID)
+		}
+		n := c.parts()
+		line("FN:%s", escapeText(c.DisplayName(GivenFirst)))
+		line("N:%s", joinVCardFields(n.Family, n.Given, n.Middle, n.Prefix, n.Suffix))
+		if n.Nickname != "" {
+			line("NICKNAME:%s", escapeText(n.Nickname))
+		}
+		for _, e := range c.Emails {
+			line("EMAIL%s:%s", vcardParams(e.Label, e.Primary && len(c.Emails) > 1), escapeText(e.Address))
+		}
+		for _, p := range c.Phones {
+			params := vcardParams(p.Label, p.Primary && len(c.Phones) > 1)
+			if strings.HasPrefix(p.Number, "+") {
+				line("TEL;VALUE=uri%s:tel:%s", params, p.Number)
+			} else {
+				// numbers older versions saved as typed
+				line("TEL;VALUE=text%s:%s", params, escapeText(p.Number))
+			}
+		}
+		for _, a := range c.Addresses {
+			line("ADR%s:%s", vcardParams(a.Label, false), joinVCardFields("", "", a.Street, a.City, a.Region, a.PostalCode, a.Country))
+		}
+		if c.Organization != "" || c.Department != "" {
+			line("ORG:%s", joinVCardFields(c.Organization, c.Department))
+		}
+		if c.Title != "" {
+			line("TITLE:%s", escapeText(c.Title))
+		}
+		for _, e := range c.Events {
+			switch e.Label {
+			case EventBirthday:
+				line("BDAY:%s", vcardDate(e.Date))
+			case EventAnniversary:
+				line("ANNIVERSARY:%s", vcardDate(e.Date))
+			}
+		}
+		if c.Notes != "" {
+			line("NOTE:%s", escapeText(c.Notes))
+		}
+		if len(c.Tags) > 0 {
+			tags := make([]string, len(c.Tags))
+			for i, tag := range c.Tags {
+				tags[i] = escapeText(tag)
+			}
+			line("CATEGORIES:%s", strings.Join(tags, ","))
+		}
+		if !c.Updated.IsZero() {
+			line("REV:%s", c.Updated.UTC().Format("20060102T150405Z"))
+		}
+		line("END:VCARD")
+	}
+	return bw.Flush()
+}
+
+// vcardParams renders the TYPE and PREF parameters of an entry.
+func vcardParams(label string, primary bool) string {
+	s := ""
+	switch label {
+	case "":
+	case LabelMobile:
+		s = ";TYPE=cell"
+	default:
+		s = ";TYPE=" + label
+	}
+	if primary {
+		s += ";PREF=1"
+	}
+	return s
+}
+
+// joinVCardFields renders the fields of a structured value such as N or
+// ADR, escaped and separated by semicolons.
+func joinVCardFields(fields ...string) string {
+	for i, f := range fields {
+		fields[i] = escapeText(f)
+	}
+	return strings.Join(fields, ";")
+}
+
+// vcardDate renders a date as 19900517, or --0517 without the year.
+func vcardDate(d Date) string {
+	if d.Year == 0 {
+		return fmt.Sprintf("--%02d%02d", d.Month, d.Day)
+	}
+	return fmt.Sprintf("%04d%02d%02d", d.Year, d.Month, d.Day)
+}
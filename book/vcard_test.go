@@ -0,0 +1,185 @@
+package book
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadVCards(t *testing.T) {
+	tests := []struct {
+		name string
+		card string
+		want Contact
+	}{
+		{
+			name: "2.1",
+			card: "BEGIN:VCARD\r\n" +
+				"VERSION:2.1\r\n" +
+				"N;CHARSET=ISO-8859-1:M\xfcller;J\xfcrgen;;;\r\n" +
+				"FN;CHARSET=ISO-8859-1:J\xfcrgen M\xfcller\r\n" +
+				"TEL;CELL;PREF:050 123 4567\r\n" +
+				"TEL;WORK;VOICE:+49 30 1234567\r\n" +
+				"EMAIL;INTERNET:juergen@example.com\r\n" +
+				"ADR;HOME;ENCODING=QUOTED-PRINTABLE:;;Hauptstra=C3=9Fe 1;Berlin;;10115;=\r\n" +
+				"Germany\r\n" +
+				"NOTE;ENCODING=QUOTED-PRINTABLE:First line=0D=0ASecond line\r\n" +
+				"END:VCARD\r\n",
+			want: Contact{
+				Name:      "Jürgen Müller",
+				NameParts: NameParts{Given: "Jürgen", Family: "Müller"},
+				Emails:    []Email{{Address: "juergen@example.com", Primary: true}},
+				Phones: []Phone{
+					{Label: LabelMobile, Number: "+971501234567", Primary: true},
+					{Label: LabelWork, Number: "+49301234567"},
+				},
+				Addresses: []Address{{Label: LabelHome, Street: "Hauptstraße 1", City: "Berlin", PostalCode: "10115", Country: "Germany"}},
+				Notes:     "First line\nSecond line",
+			},
+		},
+		{
+			name: "3.0",
+			card: "BEGIN:VCARD\n" +
+				"VERSION:3.0\n" +
+				"N:Doe;Jane;Q.;Dr.;\n" +
+				"FN:Dr. Jane Q. Doe\n" +
+				"NICKNAME:JD,Janie\n" +
+				"EMAIL;TYPE=INTERNET,WORK:jane@acme.example\n" +
+				"EMAIL;TYPE=INTERNET,HOME,pref:jane@example.com\n" +
+				"item1.TEL;TYPE=iPhone:+1 (415) 555-0199\n" +
+				"ORG:Acme\\, Inc.;Research;Lab\n" +
+				"TITLE:Engineer\n" +
+				"BDAY;X-APPLE-OMIT-YEAR=1604:1604-05-17\n" +
+				"NOTE:Likes tea\\; not coffee.\\nCall after 5\n" +
+				"  pm.\n" +
+				"CATEGORIES:Friends,Work\\, Team\n" +
+				"PHOTO;ENCODING=b;TYPE=JPEG:/9j/4AAQSkZJRgABAQ\n" +
+				"END:VCARD\n",
+			want: Contact{
+				Name:      "Dr. Jane Q. Doe",
+				NameParts: NameParts{Prefix: "Dr.", Given: "Jane", Middle: "Q.", Family: "Doe", Nickname: "JD, Janie"},
+				Emails: []Email{
+					{Label: LabelWork, Address: "jane@acme.example"},
+					{Label: LabelHome, Address: "jane@example.com", Primary: true},
+				},
+				Phones:       []Phone{{Label: LabelMobile, Number: "+14155550199", Primary: true}},
+				Organization: "Acme, Inc.",
+				Department:   "Research, Lab",
+				Title:        "Engineer",
+				// an escaped comma does not split a category, and tags cannot hold one
+				Tags:   []string{"Friends"},
+				Events: []Event{{Label: EventBirthday, Date: Date{Month: 5, Day: 17}}},
+				Notes:  "Likes tea; not coffee.\nCall after 5 pm.",
+			},
+		},
+		{
+			name: "4.0",
+			card: "BEGIN:VCARD\r\n" +
+				"VERSION:4.0\r\n" +
+				"FN:Omar al-Farsi\r\n" +
+				"TEL;VALUE=uri;TYPE=\"work,voice\";PREF=1:tel:+971-4-123-4567\r\n" +
+				"TEL;TYPE=cell:050 765 4321\r\n" +
+				"EMAIL;TYPE=work:mailto:omar@acme.example\r\n" +
+				"ADR;TYPE=work;LABEL=\"Tower B\":;Floor 2;Tower B;Dubai;;;AE\r\n" +
+				"BDAY:--0517\r\n" +
+				"ANNIVERSARY:20150601T120000Z\r\n" +
+				"END:VCARD\r\n",
+			want: Contact{
+				Name:      "Omar al-Farsi",
+				NameParts: NameParts{Given: "Omar", Family: "al-Farsi"},
+				Emails:    []Email{{Label: LabelWork, Address: "omar@acme.example", Primary: true}},
+				Phones: []Phone{
+					{Label: LabelWork, Number: "+97141234567", Primary: true},
+					{Label: LabelMobile, Number: "+971507654321"},
+				},
+				Addresses: []Address{{Label: LabelWork, Street: "Floor 2\nTower B", City: "Dubai", Country: "AE"}},
+				Events: []Event{
+					{Label: EventBirthday, Date: Date{Month: 5, Day: 17}},
+					{Label: EventAnniversary, Date: Date{Year: 2015, Month: 6, Day: 1}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contacts, rejected, err := ReadVCards(strings.NewReader(tt.card), "AE")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rejected) > 0 {
+				t.Fatalf("rejected %v", rejected)
+			}
+			if len(contacts) != 1 || !reflect.DeepEqual(contacts[0], tt.want) {
+				t.Errorf("contacts =\n%+v\nwant\n%+v", contacts, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadVCardsRejects(t *testing.T) {
+	input := "BEGIN:VCARD\nVERSION:3.0\nFN:Jane Doe\nEMAIL:jane@example.com\nEND:VCARD\n" +
+		"BEGIN:VCARD\nVERSION:3.0\nFN:John Roe\nEMAIL:not an email\nEND:VCARD\n" +
+		"BEGIN:VCARD\nVERSION:3.0\nEMAIL:ann@example.com\nEND:VCARD\n" +
+		"BEGIN:VCARD\nVERSION:5.0\nFN:Bob Loe\nEND:VCARD\n" +
+		"BEGIN:VCARD\nVERSION:3.0\nFN:Eve Moe\nTEL:12\nEND:VCARD\n" +
+		"BEGIN:VCARD\nVERSION:3.0\nFN:Max Noe\n"
+	contacts, rejected, err := ReadVCards(strings.NewReader(input), "AE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contacts) != 1 || contacts[0].Name != "Jane Doe" {
+		t.Errorf("contacts = %+v, want Jane Doe only", contacts)
+	}
+	want := []string{
+		`line 6 (John Roe): invalid email format: "not an email"`,
+		`line 11: name is required`,
+		`line 15 (Bob Loe): unsupported vCard version "5.0"`,
+		`line 19 (Eve Moe): phone "12": invalid phone number: AE numbers have 8 or 9 digits after the leading 0`,
+		`line 24 (Max Noe): card has no END:VCARD`,
+	}
+	var reasons []string
+	for _, r := range rejected {
+		reasons = append(reasons, r.String())
+	}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("rejected =\n%s\nwant\n%s", strings.Join(reasons, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, _, err := ReadVCards(strings.NewReader("Name,Email\nJane,jane@example.com\n"), "AE"); err == nil {
+		t.Error("ReadVCards of a file without cards succeeded")
+	}
+}
+
+func TestVCardRoundTrip(t *testing.T) {
+	jane := Contact{
+		Name:      "Dr. Jane Q. Doe",
+		NameParts: NameParts{Prefix: "Dr.", Given: "Jane", Middle: "Q.", Family: "Doe", Nickname: "JD"},
+		Emails: []Email{
+			{Label: LabelWork, Address: "jane@acme.example", Primary: true},
+			{Label: LabelHome, Address: "jane@example.com"},
+		},
+		Phones:       []Phone{{Label: LabelMobile, Number: "+971501234567", Primary: true}},
+		Addresses:    []Address{{Label: LabelHome, Street: "12 Palm Street; Apt 4\nBlock B", City: "Dubai", Country: "AE"}},
+		Organization: "Acme, Inc.",
+		Department:   "Research",
+		Title:        "Engineer",
+		Tags:         []string{"Friends", "Work Team"},
+		Events: []Event{
+			{Label: EventBirthday, Date: Date{Month: 5, Day: 17}},
+			{Label: EventAnniversary, Date: Date{Year: 2015, Month: 6, Day: 1}},
+		},
+		Notes: "Likes tea; not coffee.\nCall after 5 pm, not before.",
+	}
+	var buf bytes.Buffer
+	if err := WriteVCards(&buf, []Contact{jane}); err != nil {
+		t.Fatal(err)
+	}
+	contacts, rejected, err := ReadVCards(&buf, "AE")
+	if err != nil || len(rejected) > 0 {
+		t.Fatalf("ReadVCards = %v, %v", rejected, err)
+	}
+	if len(contacts) != 1 || !reflect.DeepEqual(contacts[0], jane) {
+		t.Errorf("read back\n%+v\nwant\n%+v", contacts, jane)
+	}
+}
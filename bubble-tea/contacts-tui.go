@@ -1,19 +1,17 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
-	"log"
 	"os"
-	"regexp"
 	"strings"
+	"time"
 
+	"contact-book/book"
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
 )
 
 // Screen/State
@@ -26,33 +24,59 @@ const (
 	searchScreen               // Search
 	deleteScreen               // Delete
 	editScreen                 // Edit
-	filePath     = "contacts.txt"
+	detailScreen               // Every field of one contact
 )
 
-type Contact struct {
-	Name   string
-	Email  string
-	Mobile string
-}
-
 type model struct {
+	store         book.Store
+	region        string
+	phoneStyle    book.PhoneStyle
+	nameOrder     book.NameOrder
+	schema        book.Schema // custom fields, shown as extra columns
 	currentScreen screen
 	cursor        int
-	contacts      []Contact
+	contacts      []book.Contact
 	table         table.Model
 	focusIndex    int
 	inputs        []textinput.Model
+	query         textinput.Model
+	editing       book.Contact // contact shown in the form when editing
+	shown         book.Contact // contact on the detail screen
+	grouped       bool         // list grouped by organization
+	sorted        bool         // list sorted by name
+	group         int          // group shown in the list, 0 for every contact
+	upcoming      []book.Occurrence
 	errorMsg      string
 }
 
-func initialModel() model {
-	return model{
+// days ahead shown in the coming up panel of the menu
+const upcomingDays = 30
+
+func initialModel(store book.Store, region string, phoneStyle book.PhoneStyle, nameOrder book.NameOrder, schema book.Schema) model {
+	m := model{
+		store:         store,
+		region:        region,
+		phoneStyle:    phoneStyle,
+		nameOrder:     nameOrder,
+		schema:        schema,
 		currentScreen: menuScreen,
 		cursor:        0,
-		contacts:      []Contact{},
+		contacts:      []book.Contact{},
 		inputs:        initialInputs(),
 		focusIndex:    0,
 	}
+	return m.refreshUpcoming()
+}
+
+// read the book again for the coming up panel
+func (m model) refreshUpcoming() model {
+	contacts, err := m.store.List()
+	if err != nil {
+		m.errorMsg = err.Error()
+		return m
+	}
+	m.upcoming = book.Upcoming(contacts, time.Now(), upcomingDays)
+	return m
 }
 
 func (m model) Init() tea.Cmd {
@@ -63,8 +87,76 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	// table handling
 	if m.currentScreen == listScreen {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "enter":
+				if contact, ok := m.selectedContact(); ok {
+					m.shown = contact
+					m.currentScreen = detailScreen
+				}
+				return m, nil
+			case "g":
+				m.grouped = !m.grouped
+				m.table.SetRows(m.listRows())
+				m.table.GotoTop()
+				return m, nil
+			case "s":
+				m.sorted = !m.sorted
+				m.table.SetRows(m.listRows())
+				m.table.GotoTop()
+				return m, nil
+			case "f":
+				// star or unstar the selected contact
+				contact, ok := m.selectedContact()
+				if !ok {
+					return m, nil
+				}
+				contact.Favorite = !contact.Favorite
+				if err := m.store.Update(contact); err != nil {
+					m.errorMsg = "Could not save contact: " + err.Error()
+					return m, nil
+				}
+				contacts, err := m.store.List()
+				if err != nil {
+					m.errorMsg = err.Error()
+					return m, nil
+				}
+				m.errorMsg = ""
+				m.contacts = contacts
+				m.table.SetRows(m.listRows())
+				return m, nil
+			case "tab", "shift+tab":
+				// move through the group sidebar
+				count := len(book.Tags(m.contacts)) + 1
+				if msg.String() == "tab" {
+					m.group = (m.group + 1) % count
+				} else {
+					m.group = (m.group + count - 1) % count
+				}
+				m.table.SetRows(m.listRows())
+				m.table.GotoTop()
+				return m, nil
+			}
+		}
 		m.table, cmd = m.table.Update(msg)
 	}
+	if m.currentScreen == detailScreen {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "esc":
+				m.currentScreen = listScreen
+			case "ctrl+c", "q":
+				return m, tea.Quit
+			}
+		}
+		return m, nil
+	}
+	if m.currentScreen == searchScreen {
+		return m.updateSearch(msg)
+	}
+	if m.currentScreen == editScreen {
+		return m.updatePicker(msg)
+	}
 	if m.currentScreen == addScreen {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
@@ -77,54 +169,41 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			case "tab", "down":
 				m.focusIndex++
-				if m.focusIndex > len(m.inputs)-1 {
+				if m.focusIndex > m.formSize()-1 {
 					m.focusIndex = 0
 				}
 
 			case "shift+tab", "up":
 				m.focusIndex--
 				if m.focusIndex < 0 {
-					m.focusIndex = len(m.inputs) - 1
+					m.focusIndex = m.formSize() - 1
 				}
 
 			case "ctrl+s":
 				// save contacts
-				name := m.inputs[0].Value()
-				email := m.inputs[1].Value()
-				mobile := m.inputs[2].Value()
-				if name == "" || email == "" || mobile == "" {
-					m.errorMsg = "All fields are required"
+				contact, err := m.formContact()
+				if err != nil {
+					m.errorMsg = capitalizeFirst(err.Error())
 					return m, nil
 				}
-				// Validate email
-				if !isValidEmail(email) {
-					// TODO: Show error message to user
-					m.errorMsg = "Invalid email format"
-					return m, nil
+				// Save to file
+				if m.editing.ID != "" {
+					err = m.store.Update(contact)
+				} else {
+					_, err = m.store.Add(contact)
 				}
-
-				// Validate mobile
-				if !isValidMobile(mobile) {
-					// TODO: Show error message to user
-					m.errorMsg = "Mobile must be 10 digits starting with 0"
+				if err != nil {
+					m.errorMsg = "Could not save contact: " + err.Error()
 					return m, nil
 				}
-				// Format and capitalize
-				name = capitalizeName(name)
-				mobile = formatMobile(mobile)
-				// Save to file
-				saveContact(Contact{
-					Name:   name,
-					Email:  email,
-					Mobile: mobile,
-				})
 
 				// Reset inputs and go back to menu
 				m.inputs = initialInputs()
+				m.editing = book.Contact{}
 				m.errorMsg = ""
 				m.currentScreen = menuScreen
 				m.cursor = 0
-				return m, nil
+				return m.refreshUpcoming(), nil
 			}
 			// Update which input has focus
 			for i := range m.inputs {
@@ -163,14 +242,39 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Handle menu selection (we'll add this next)
 			switch m.cursor {
 			case 0: // List Contacts
-				m.contacts = loadContacts()
-				m.table = makeContactTable(m.contacts)
+				contacts, err := m.store.List()
+				if err != nil {
+					m.errorMsg = err.Error()
+					return m, nil
+				}
+				m.errorMsg = ""
+				m.contacts = contacts
+				m.group = min(m.group, len(book.Tags(m.contacts)))
+				m.table = m.makeContactTable(m.contacts)
+				m.table.SetRows(m.listRows())
 				m.currentScreen = listScreen
 			case 1: // add contacts
 				m.inputs = initialInputs()
+				m.editing = book.Contact{}
 				m.focusIndex = 0
 				m.currentScreen = addScreen
 
+			case 2, 4: // search, or pick the contact to edit
+				contacts, err := m.store.List()
+				if err != nil {
+					m.errorMsg = err.Error()
+					return m, nil
+				}
+				m.errorMsg = ""
+				m.contacts = contacts
+				m.table = m.makeContactTable(m.contacts)
+				m.currentScreen = editScreen
+				if m.cursor == 2 {
+					m.query = initialQuery()
+					m.table.Blur()
+					m.currentScreen = searchScreen
+				}
+
 			case 5: // Exit
 				return m, tea.Quit
 			}
@@ -180,18 +284,186 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func makeContactTable(contacts []Contact) table.Model {
-	columns := []table.Column{
-		{Title: "Name", Width: 20},
-		{Title: "Email", Width: 30},
-		{Title: "Mobile", Width: 20},
+// search screen: typing filters the table, arrows move in it
+func (m model) updateSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc":
+			m.currentScreen = menuScreen
+			m.cursor = 0
+			return m, nil
+		case "up", "down", "pgup", "pgdown":
+			m.table.Focus()
+			m.table, cmd = m.table.Update(msg)
+			m.table.Blur()
+			return m, cmd
+		case "enter":
+			return m.openEditor()
+		}
+	}
+	m.query, cmd = m.query.Update(msg)
+	m.table.SetRows(m.contactRows(m.matches()))
+	return m, cmd
+}
+
+// edit screen: pick a contact in the table and open it in the form
+func (m model) updatePicker(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc":
+			m.currentScreen = menuScreen
+			m.cursor = 0
+			return m, nil
+		case "ctrl+c", "q":
+			return m, tea.Quit
+		case "enter":
+			return m.openEditor()
+		}
 	}
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// contacts matching the search query
+func (m model) matches() []book.Contact {
+	var found []book.Contact
+	for _, c := range m.contacts {
+		if c.Matches(strings.TrimSpace(m.query.Value())) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// the contact of the selected table row
+func (m model) selectedContact() (book.Contact, bool) {
+	row := m.table.SelectedRow()
+	if row == nil {
+		return book.Contact{}, false
+	}
+	for _, c := range m.contacts {
+		if c.ShortID() == row[1] {
+			return c, true
+		}
+	}
+	return book.Contact{}, false
+}
+
+// open the selected table row in the form
+func (m model) openEditor() (tea.Model, tea.Cmd) {
+	contact, ok := m.selectedContact()
+	if !ok {
+		return m, nil
+	}
+	return m.fillForm(contact), nil
+}
 
+// the form filled in with contact, ready for editing
+func (m model) fillForm(contact book.Contact) model {
+	m.editing = contact
+	m.inputs = initialInputs()
+	m.inputs[0].SetValue(m.editing.Name)
+	m.inputs[1].SetValue(book.JoinEmails(m.editing.Emails))
+	m.inputs[2].SetValue(book.JoinPhones(m.editing.Phones))
+	m.inputs[3].SetValue(book.JoinAddresses(m.editing.Addresses))
+	m.inputs[4].SetValue(m.editing.Organization)
+	m.inputs[5].SetValue(m.editing.Department)
+	m.inputs[6].SetValue(m.editing.Title)
+	m.inputs[7].SetValue(strings.Join(m.editing.Tags, ", "))
+	m.inputs[8].SetValue(book.JoinEvents(m.editing.Events))
+	var fields []string
+	for _, f := range m.schema.Fields {
+		if value := m.editing.Field(f.Name); value != "" {
+			fields = append(fields, f.Name+"="+value)
+		}
+	}
+	m.inputs[9].SetValue(strings.Join(fields, "; "))
+	m.focusIndex = 0
+	m.errorMsg = ""
+	m.currentScreen = addScreen
+	return m
+}
+
+// one table row per contact: the primary email and phone, with a count of
+// the others
+func (m model) contactRows(contacts []book.Contact) []table.Row {
 	rows := []table.Row{}
 	for _, c := range contacts {
-		rows = append(rows, table.Row{c.Name, c.Email, c.Mobile})
+		email := withMore(c.PrimaryEmail(), len(c.Emails))
+		phone := withMore(book.FormatPhone(c.PrimaryPhone(), m.phoneStyle, m.region), len(c.Phones))
+		star := ""
+		if c.Favorite {
+			star = "★"
+		}
+		row := table.Row{star, c.ShortID(), c.DisplayName(m.nameOrder), email, phone, c.Organization}
+		for _, f := range m.schema.Fields {
+			row = append(row, c.Field(f.Name))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// the rows of the list screen, sorted by name in the display order (so by
+// family name when it comes first) and under a heading row per organization
+// when asked for
+func (m model) listRows() []table.Row {
+	contacts := m.contacts
+	if m.group > 0 {
+		contacts = nil
+		tag := book.Tags(m.contacts)[m.group-1].Tag
+		for _, c := range m.contacts {
+			if c.HasTag(tag) {
+				contacts = append(contacts, c)
+			}
+		}
+	}
+	contacts = append([]book.Contact(nil), contacts...)
+	if m.sorted {
+		book.SortByName(contacts, m.nameOrder)
+	}
+	book.FavoritesFirst(contacts)
+	if !m.grouped {
+		return m.contactRows(contacts)
+	}
+	rows := []table.Row{}
+	for _, group := range book.GroupByOrganization(contacts) {
+		name := group.Organization
+		if name == "" {
+			name = "No organization"
+		}
+		heading := make(table.Row, 6+len(m.schema.Fields))
+		heading[2] = fmt.Sprintf("── %s (%d)", name, len(group.Contacts))
+		rows = append(rows, heading)
+		rows = append(rows, m.contactRows(group.Contacts)...)
+	}
+	return rows
+}
+
+func withMore(primary string, count int) string {
+	if count > 1 {
+		return fmt.Sprintf("%s (+%d)", primary, count-1)
+	}
+	return primary
+}
+
+func (m model) makeContactTable(contacts []book.Contact) table.Model {
+	columns := []table.Column{
+		{Title: "★", Width: 1},
+		{Title: "ID", Width: 8},
+		{Title: "Name", Width: 20},
+		{Title: "Email", Width: 30},
+		{Title: "Phone", Width: 24},
+		{Title: "Organization", Width: 16},
+	}
+	for _, f := range m.schema.Fields {
+		columns = append(columns, table.Column{Title: f.Heading(), Width: max(len([]rune(f.Heading())), 12)})
 	}
 
+	rows := m.contactRows(contacts)
+
 	t := table.New(
 		table.WithColumns(columns),
 		table.WithRows(rows),
@@ -214,57 +486,88 @@ func makeContactTable(contacts []Contact) table.Model {
 	return t
 }
 
-func saveContact(contact Contact) {
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+// formContact reads the form into a contact. When editing, the fields the
+// form does not show, such as the notes, the interaction log and the
+// favorite star, are kept from the contact being edited.
+func (m model) formContact() (book.Contact, error) {
+	name := m.inputs[0].Value()
+	form, err := book.NewContact(name, book.SplitEntries(m.inputs[1].Value()), nil, m.region)
 	if err != nil {
-		return
+		return book.Contact{}, err
+	}
+	contact := m.editing
+	// an unchanged name keeps the parts it was split into
+	if m.editing.ID == "" || name != m.editing.Name {
+		contact.Name, contact.NameParts = form.Name, form.NameParts
+	}
+	contact.Emails = form.Emails
+	// phones left as they were shown are kept as stored, so numbers saved
+	// before they were checked do not stop the rest of the edit
+	stored := map[string]book.Phone{}
+	if shown := book.SplitEntries(book.JoinPhones(m.editing.Phones)); len(shown) == len(m.editing.Phones) {
+		for i, entry := range shown {
+			stored[entry] = m.editing.Phones[i]
+		}
+	}
+	contact.Phones = nil
+	for _, entry := range book.SplitEntries(m.inputs[2].Value()) {
+		phone, ok := stored[entry]
+		if !ok {
+			if phone, err = book.ParsePhoneEntry(entry, m.region); err != nil {
+				return book.Contact{}, err
+			}
+		}
+		contact.Phones = append(contact.Phones, phone)
 	}
-	defer func() {
-		err = file.Close()
+	contact.Normalize()
+	contact.Addresses = nil
+	for _, entry := range book.SplitEntries(m.inputs[3].Value()) {
+		address, err := book.ParseAddress(entry)
 		if err != nil {
-			log.Fatalf("Error closing file %v\n:", err)
+			return book.Contact{}, err
 		}
-	}()
-
-	fmt.Fprintf(file, "%s,%s,%s\n", contact.Name, contact.Email, contact.Mobile)
-}
-
-func loadContacts() []Contact {
-	var contacts []Contact
-	var err error
-	var file *os.File
-
-	file, err = os.OpenFile(filePath, os.O_RDONLY, 0o644)
-	if err != nil {
-		log.Fatalf("Error Opening the file %v\n", err)
-		return contacts
+		contact.Addresses = append(contact.Addresses, address)
 	}
-
-	defer func() {
-		err = file.Close()
+	contact.Organization = strings.TrimSpace(m.inputs[4].Value())
+	contact.Department = strings.TrimSpace(m.inputs[5].Value())
+	contact.Title = strings.TrimSpace(m.inputs[6].Value())
+	contact.Tags = nil
+	for _, entry := range strings.FieldsFunc(m.inputs[7].Value(), func(r rune) bool { return r == ',' || r == ';' }) {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		tag, err := book.NormalizeTag(entry)
 		if err != nil {
-			log.Fatalf("Error closing file %v\n:", err)
+			return book.Contact{}, err
 		}
-	}()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		parts := strings.Split(line, ",")
-		if len(parts) == 3 {
-			contacts = append(contacts, Contact{
-				Name:   parts[0],
-				Email:  parts[1],
-				Mobile: parts[2],
-			})
+		contact.AddTag(tag)
+	}
+	contact.Events = nil
+	for _, entry := range book.SplitEntries(m.inputs[8].Value()) {
+		event, err := book.ParseEvent(entry)
+		if err != nil {
+			return book.Contact{}, err
 		}
+		contact.SetEvent(event.Label, event.Date)
 	}
-
-	return contacts
+	// fields the schema no longer declares are kept as they are
+	contact.Fields = nil
+	for name, value := range m.editing.Fields {
+		if _, ok := m.schema.Field(name); !ok {
+			contact.SetField(name, value)
+		}
+	}
+	for _, entry := range book.SplitEntries(m.inputs[9].Value()) {
+		name, value, _ := strings.Cut(entry, "=")
+		if err := m.schema.Set(&contact, name, value); err != nil {
+			return book.Contact{}, err
+		}
+	}
+	return contact, nil
 }
 
 func initialInputs() []textinput.Model {
-	inputs := make([]textinput.Model, 3)
+	inputs := make([]textinput.Model, 10)
 
 	inputs[0] = textinput.New()
 	inputs[0].Placeholder = "Name"
@@ -273,43 +576,76 @@ func initialInputs() []textinput.Model {
 	inputs[0].Width = 30
 
 	inputs[1] = textinput.New()
-	inputs[1].Placeholder = "Email"
-	inputs[1].CharLimit = 50
-	inputs[1].Width = 30
+	inputs[1].Placeholder = "work:jane@acme.com; home:jane@mail.com"
+	inputs[1].CharLimit = 200
+	inputs[1].Width = 50
 
 	inputs[2] = textinput.New()
-	inputs[2].Placeholder = "Mobile"
-	inputs[2].CharLimit = 15
-	inputs[2].Width = 30
+	inputs[2].Placeholder = "mobile:050 123 4567; work:+44 20 7946 0958"
+	inputs[2].CharLimit = 200
+	inputs[2].Width = 50
+
+	inputs[3] = textinput.New()
+	inputs[3].Placeholder = "home:12 Palm Street|Dubai||00000|AE (optional)"
+	inputs[3].CharLimit = 400
+	inputs[3].Width = 50
+
+	for i, placeholder := range []string{"Organization", "Department", "Job title"} {
+		inputs[4+i] = textinput.New()
+		inputs[4+i].Placeholder = placeholder + " (optional)"
+		inputs[4+i].CharLimit = 50
+		inputs[4+i].Width = 30
+	}
+
+	inputs[7] = textinput.New()
+	inputs[7].Placeholder = "family, book club (optional)"
+	inputs[7].CharLimit = 200
+	inputs[7].Width = 50
+
+	inputs[8] = textinput.New()
+	inputs[8].Placeholder = "birthday:1990-05-17; anniversary:--06-12 (optional)"
+	inputs[8].CharLimit = 200
+	inputs[8].Width = 50
+
+	inputs[9] = textinput.New()
+	inputs[9].Placeholder = "name=value; name=value (optional)"
+	inputs[9].CharLimit = 400
+	inputs[9].Width = 50
 
 	return inputs
 }
 
-func isValidEmail(email string) bool {
-	pattern := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
-	matched, _ := regexp.MatchString(pattern, email)
-	return matched
+// the number of inputs the form shows; the custom fields input is left out
+// when none are declared
+func (m model) formSize() int {
+	if len(m.schema.Fields) == 0 {
+		return len(m.inputs) - 1
+	}
+	return len(m.inputs)
 }
 
-func isValidMobile(mobile string) bool {
-	// Check length and starts with 0
-	if len(mobile) != 10 {
-		return false
-	}
-	if !strings.HasPrefix(mobile, "0") {
-		return false
-	}
-	return true
+func initialQuery() textinput.Model {
+	query := textinput.New()
+	query.Placeholder = "Name, email, phone or address"
+	query.Focus()
+	query.CharLimit = 50
+	query.Width = 30
+	return query
 }
 
-func formatMobile(mobile string) string {
-	// Replace leading 0 with +971
-	return strings.Replace(mobile, "0", "+971", 1)
+// capitalizeFirst upper cases the first letter of a validation message.
+func capitalizeFirst(msg string) string {
+	if msg == "" {
+		return msg
+	}
+	return strings.ToUpper(msg[:1]) + msg[1:]
 }
 
-func capitalizeName(name string) string {
-	caser := cases.Title(language.English)
-	return caser.String(name)
+func renderError(msg string) string {
+	errorStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("196")). // Red
+		Bold(true)
+	return errorStyle.Render("❌ " + msg)
 }
 
 func (m model) View() string {
@@ -326,6 +662,11 @@ func (m model) View() string {
 			s += fmt.Sprintf("%s %s\n", cursor, choice)
 		}
 
+		s = lipgloss.JoinHorizontal(lipgloss.Top, s, "    ", m.comingUp())
+		s += "\n"
+		if m.errorMsg != "" {
+			s += "\n" + renderError(m.errorMsg) + "\n"
+		}
 		s += "\nUse arrow keys to navigate, Enter to select, q to quit\n"
 		return s
 	}
@@ -336,24 +677,72 @@ func (m model) View() string {
 			Padding(1, 30).
 			Render("Contact List")
 		footer := fmt.Sprintf("\nTotal: %d contacts\n", len(m.contacts))
-		return s + "\n\n" + m.table.View() + footer + "\nPress ESC to go back, and 'q' to quit\n"
+		if m.errorMsg != "" {
+			footer += "\n" + renderError(m.errorMsg) + "\n"
+		}
+		body := lipgloss.JoinHorizontal(lipgloss.Top, m.groupSidebar(), " ", m.table.View())
+		return s + "\n\n" + body + footer + "\nPress Enter for details, Tab to change group, 's' to sort by name,\n'g' to group by organization, 'f' to star a favorite, ESC to go back, and 'q' to quit\n"
+	}
+	if m.currentScreen == detailScreen {
+		return m.detailView(m.shown) + "\nPress ESC to go back to the list, and 'q' to quit\n"
+	}
+	if m.currentScreen == searchScreen {
+		s := "Search Contacts\n\n"
+		s += m.query.View() + "\n\n"
+		s += m.table.View()
+		s += fmt.Sprintf("\n%d of %d contacts\n", len(m.table.Rows()), len(m.contacts))
+		s += "\nType to filter, arrows to move, Enter to edit, ESC to go back\n"
+		return s
+	}
+	if m.currentScreen == editScreen {
+		s := "Edit Contact\n\n"
+		s += m.table.View()
+		s += "\nPick a contact and press Enter to edit it, ESC to go back\n"
+		return s
 	}
 	if m.currentScreen == addScreen {
 		s := "Add New Contact\n\n"
+		if m.editing.ID != "" {
+			s = "Edit Contact " + m.editing.ShortID() + "\n\n"
+		}
 		if m.errorMsg != "" {
-			errorStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color("196")). // Red
-				Bold(true)
-			s += errorStyle.Render("❌ "+m.errorMsg) + "\n\n"
+			s += renderError(m.errorMsg) + "\n\n"
 		}
 		s += "Name:\n"
 		s += m.inputs[0].View() + "\n\n"
 
-		s += "Email:\n"
+		s += "Emails:\n"
 		s += m.inputs[1].View() + "\n\n"
 
-		s += "Mobile:\n"
+		s += "Phones:\n"
 		s += m.inputs[2].View() + "\n\n"
+
+		s += "Addresses:\n"
+		s += m.inputs[3].View() + "\n\n"
+
+		s += "Organization, department and title:\n"
+		s += m.inputs[4].View() + "\n"
+		s += m.inputs[5].View() + "\n"
+		s += m.inputs[6].View() + "\n\n"
+
+		s += "Groups:\n"
+		s += m.inputs[7].View() + "\n\n"
+
+		s += "Dates:\n"
+		s += m.inputs[8].View() + "\n\n"
+
+		if len(m.schema.Fields) > 0 {
+			var names []string
+			for _, f := range m.schema.Fields {
+				names = append(names, f.Name)
+			}
+			s += "Custom fields (" + strings.Join(names, ", ") + "):\n"
+			s += m.inputs[9].View() + "\n\n"
+		}
+		s += "Separate several entries with ';', label them as label:value\n"
+		s += "and start an email or phone with '*' to make it primary.\n"
+		s += "Address fields are street|city|region|postal code|country\n"
+		s += "and dates YYYY-MM-DD, or --MM-DD without the year.\n\n"
 		s += "Tab to move between fields, Ctrl+S to save, ESC to cancel\n"
 		s += "ESC to cancel\n"
 
@@ -362,9 +751,216 @@ func (m model) View() string {
 	return "Other screen (TODO)"
 }
 
+// the birthdays, anniversaries and other dates of the next days
+func (m model) comingUp() string {
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("Coming up"), ""}
+	for _, o := range m.upcoming {
+		when := o.Date.Format("Mon 02 Jan")
+		switch o.Days {
+		case 0:
+			when = "Today"
+		case 1:
+			when = "Tomorrow"
+		}
+		line := fmt.Sprintf("%-10s %s, %s", when, o.Contact.DisplayName(m.nameOrder), o.Event.Label)
+		if o.Years > 0 {
+			line += fmt.Sprintf(" (%d)", o.Years)
+		}
+		lines = append(lines, line)
+	}
+	if len(m.upcoming) == 0 {
+		lines = append(lines, fmt.Sprintf("Nothing in the next %d days", upcomingDays))
+	}
+	return lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62")).
+		Padding(0, 1).
+		Render(strings.Join(lines, "\n"))
+}
+
+// the groups of the book with the one shown in the list highlighted
+func (m model) groupSidebar() string {
+	selected := lipgloss.NewStyle().Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57"))
+	lines := []string{lipgloss.NewStyle().Bold(true).Render("Groups"), ""}
+	entries := []string{fmt.Sprintf("All (%d)", len(m.contacts))}
+	for _, group := range book.Tags(m.contacts) {
+		entries = append(entries, fmt.Sprintf("%s (%d)", group.Tag, group.Count))
+	}
+	for i, entry := range entries {
+		if i == m.group {
+			entry = selected.Render(entry)
+		}
+		lines = append(lines, entry)
+	}
+	return lipgloss.NewStyle().
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		Padding(0, 1).
+		Width(20).
+		Render(strings.Join(lines, "\n"))
+}
+
+// every field of one contact
+func (m model) detailView(c book.Contact) string {
+	label := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Width(14)
+	line := func(name, value string) string {
+		return label.Render(name) + value + "\n"
+	}
+	note := func(entryLabel string, primary bool) string {
+		var notes []string
+		if entryLabel != "" {
+			notes = append(notes, entryLabel)
+		}
+		if primary {
+			notes = append(notes, "primary")
+		}
+		if len(notes) == 0 {
+			return ""
+		}
+		return " (" + strings.Join(notes, ", ") + ")"
+	}
+
+	s := lipgloss.NewStyle().Bold(true).Render(c.DisplayName(m.nameOrder)) + "\n\n"
+	s += line("ID", c.ID)
+	for _, field := range [][2]string{
+		{"Prefix", c.NameParts.Prefix},
+		{"Given name", c.NameParts.Given},
+		{"Middle name", c.NameParts.Middle},
+		{"Family name", c.NameParts.Family},
+		{"Suffix", c.NameParts.Suffix},
+		{"Nickname", c.NameParts.Nickname},
+	} {
+		if field[1] != "" {
+			s += line(field[0], field[1])
+		}
+	}
+	for _, e := range c.Emails {
+		s += line("Email", e.Address+note(e.Label, e.Primary))
+	}
+	for _, p := range c.Phones {
+		s += line("Phone", book.FormatPhone(p.Number, m.phoneStyle, m.region)+note(p.Label, p.Primary))
+	}
+	for _, a := range c.Addresses {
+		lines := a.Lines()
+		if len(lines) == 0 {
+			continue
+		}
+		s += line("Address", lines[0]+note(a.Label, false))
+		for _, l := range lines[1:] {
+			s += line("", l)
+		}
+	}
+	for _, field := range [][2]string{
+		{"Organization", c.Organization},
+		{"Department", c.Department},
+		{"Title", c.Title},
+		{"Manager", m.contactName(c.ManagerID)},
+		{"Assistant", m.contactName(c.AssistantID)},
+	} {
+		if field[1] != "" {
+			s += line(field[0], field[1])
+		}
+	}
+	if c.Favorite {
+		s += line("Favorite", "★")
+	}
+	if len(c.Tags) > 0 {
+		s += line("Groups", strings.Join(c.Tags, ", "))
+	}
+	for _, e := range c.Events {
+		s += line(capitalizeFirst(e.Label), e.Date.String())
+	}
+	for _, f := range m.schema.Fields {
+		if value := c.Field(f.Name); value != "" {
+			s += line(f.Heading(), value)
+		}
+	}
+	if c.Notes != "" {
+		lines := strings.Split(c.Notes, "\n")
+		s += line("Notes", lines[0])
+		for _, l := range lines[1:] {
+			s += line("", l)
+		}
+	}
+	// the latest interactions, newest first
+	for i := len(c.Interactions) - 1; i >= 0 && i >= len(c.Interactions)-3; i-- {
+		entry := c.Interactions[i]
+		name := ""
+		if i == len(c.Interactions)-1 {
+			name = "Contacted"
+		}
+		text := entry.At.Local().Format("2006-01-02 15:04") + " " + entry.Kind
+		if entry.Text != "" {
+			text += ": " + entry.Text
+		}
+		s += line(name, text)
+	}
+	for _, stamp := range []struct {
+		name string
+		at   time.Time
+	}{{"Created", c.Created}, {"Updated", c.Updated}} {
+		if !stamp.at.IsZero() {
+			s += line(stamp.name, stamp.at.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	return s
+}
+
+// the name of the contact with this ID, or the ID when it is not loaded
+func (m model) contactName(id string) string {
+	if id == "" {
+		return ""
+	}
+	for _, c := range m.contacts {
+		if c.ID == id {
+			return c.DisplayName(m.nameOrder)
+		}
+	}
+	return id
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
 func main() {
+	storeKind := flag.String("store", getenv("CONTACTS_STORE", book.KindCSV), "storage backend: csv, json or memory (env CONTACTS_STORE)")
+	storePath := flag.String("file", os.Getenv("CONTACTS_FILE"), "contacts file, defaults to contacts.txt or contacts.json (env CONTACTS_FILE)")
+	region := flag.String("region", getenv("CONTACTS_REGION", book.DefaultRegion), "country of numbers typed without a country code (env CONTACTS_REGION)")
+	phoneFormat := flag.String("phone-format", getenv("CONTACTS_PHONE_FORMAT", "international"), "how phone numbers are shown: national or international (env CONTACTS_PHONE_FORMAT)")
+	order := flag.String("name-order", getenv("CONTACTS_NAME_ORDER", "given"), "how names are shown: given or family (env CONTACTS_NAME_ORDER)")
+	fields := flag.String("fields", getenv("CONTACTS_FIELDS", book.DefaultSchemaPath), "schema file declaring the custom fields (env CONTACTS_FIELDS)")
+	flag.Parse()
+
+	if !book.ValidRegion(*region) {
+		fmt.Printf("Error: unknown region %q, use one of %s\n", *region, strings.Join(book.Regions(), " "))
+		os.Exit(1)
+	}
+	phoneStyle, err := book.ParsePhoneStyle(*phoneFormat)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	nameOrder, err := book.ParseNameOrder(*order)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	store, err := book.Open(*storeKind, *storePath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	schema, err := book.LoadSchema(*fields)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 	p := tea.NewProgram(
-		initialModel(),
+		initialModel(store, strings.ToUpper(*region), phoneStyle, nameOrder, schema),
 		tea.WithAltScreen(),       // Full screen mode
 		tea.WithMouseCellMotion(), // Optional: mouse support
 	)
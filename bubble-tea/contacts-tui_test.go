@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"contact-book/book"
+)
+
+func TestFormContactKeepsHiddenFields(t *testing.T) {
+	at := time.Date(2026, 9, 30, 10, 0, 0, 0, time.UTC)
+	jane, err := book.NewContact("Jane Doe", []string{"jane@acme.com"}, []string{"+971501234567"}, "AE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jane.ID = book.NewID()
+	jane.Favorite = true
+	jane.Notes = "Met at GITEX."
+	jane.Interactions = []book.Interaction{{At: at, Kind: "met", Text: "lunch"}}
+	jane.ManagerID = book.NewID()
+
+	m := initialModel(book.NewMemoryStore(jane), "AE", book.International, book.GivenFirst, book.Schema{})
+	m = m.fillForm(jane)
+	m.inputs[4].SetValue("Acme")
+
+	got, err := m.formContact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Organization != "Acme" {
+		t.Errorf("Organization = %q, want Acme", got.Organization)
+	}
+	if !got.Favorite {
+		t.Error("editing unstarred the contact")
+	}
+	if got.Notes != jane.Notes {
+		t.Errorf("Notes = %q, want %q", got.Notes, jane.Notes)
+	}
+	if len(got.Interactions) != 1 || got.Interactions[0] != jane.Interactions[0] {
+		t.Errorf("Interactions = %v, want %v", got.Interactions, jane.Interactions)
+	}
+	if got.ID != jane.ID || got.ManagerID != jane.ManagerID {
+		t.Errorf("ID, ManagerID = %s, %s, want %s, %s", got.ID, got.ManagerID, jane.ID, jane.ManagerID)
+	}
+}
+
+func TestFormContactRequiresName(t *testing.T) {
+	m := initialModel(book.NewMemoryStore(), "AE", book.International, book.GivenFirst, book.Schema{})
+	m.inputs[1].SetValue("jane@acme.com")
+	if _, err := m.formContact(); err == nil {
+		t.Error("a contact without a name was accepted")
+	}
+}
+
+func TestFormContact(t *testing.T) {
+	legacy := book.Contact{ID: book.NewID(), Name: "John Roe", Phones: []book.Phone{{Label: "home", Number: "ext 12"}}}
+	tests := []struct {
+		name    string
+		editing book.Contact
+		phones  string
+		want    []book.Phone
+		wantErr bool
+	}{
+		{"no phone", book.Contact{}, "", nil, false},
+		{"new phone", book.Contact{}, "mobile:050 123 4567", []book.Phone{{Label: "mobile", Number: "+971501234567", Primary: true}}, false},
+		{"legacy number kept", legacy, "home:ext 12", []book.Phone{{Label: "home", Number: "ext 12", Primary: true}}, false},
+		{"legacy number changed", legacy, "home:ext 13", nil, true},
+		{"legacy number removed", legacy, "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := initialModel(book.NewMemoryStore(), "AE", book.International, book.GivenFirst, book.Schema{})
+			m = m.fillForm(tt.editing)
+			m.inputs[0].SetValue("Jane Doe")
+			m.inputs[2].SetValue(tt.phones)
+			got, err := m.formContact()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("phones %q were accepted", tt.phones)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Emails) != 0 || !reflect.DeepEqual(got.Phones, tt.want) {
+				t.Errorf("emails, phones = %v, %v, want none, %v", got.Emails, got.Phones, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,900 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"contact-book/book"
+)
+
+// exit codes of the subcommands
+const (
+	exitOK       = 0 // success
+	exitFailure  = 1 // storage or other runtime failure
+	exitUsage    = 2 // bad command line
+	exitNotFound = 3 // no contact matched
+	exitInvalid  = 4 // input failed validation
+	exitConflict = 5 // the contact changed underneath the command
+	exitAborted  = 6 // the user did not confirm
+)
+
+// a subcommand receives its arguments without the command name
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"add", "--name NAME|--given NAME... [--email [LABEL:]EMAIL]... [--phone [LABEL:]NUMBER]... [--mobile NUMBER]\n" +
+			"           [--address [LABEL:]STREET|CITY|REGION|POSTCODE|COUNTRY]...\n" +
+			"           [--org ORG] [--department DEPT] [--title TITLE] [--manager ID] [--assistant ID]\n" +
+			"           [--prefix P] [--given G] [--middle M] [--family F] [--suffix S] [--nickname N] [--tag TAG]...\n" +
+			"           [--birthday DATE] [--anniversary DATE] [--event LABEL:DATE]... [--notes TEXT] [--field NAME=VALUE]...",
+			"add a contact and print its ID", cmdAdd},
+		{"list", "[--org ORG] [--tag TAG]... [--any-tag] [--favorites] [--group-by org] [--since DATE]\n" +
+			"           [--sort name|family|contacted|updated] [--output FORMAT]",
+			"list every contact, favorites first, or everyone at ORG or with the tags", cmdList},
+		{"search", "QUERY [--org ORG] [--tag TAG]... [--any-tag] [--favorites] [--since DATE] [--sort name|family|contacted|updated] [--output FORMAT]",
+			"list the contacts whose name, email, phone, address, work details or notes contain QUERY", cmdSearch},
+		{"show", "ID [--changes]", "show every detail of a contact, and when each field last changed", cmdShow},
+		{"favorite", "ID", "star a contact, or unstar a favorite one", cmdFavorite},
+		{"edit", "ID [--name NAME] [--email|--add-email|--remove-email|--primary-email EMAIL]...\n" +
+			"           [--phone|--add-phone|--remove-phone|--primary-phone NUMBER]...\n" +
+			"           [--address|--add-address ADDRESS]... [--remove-address LABEL]...\n" +
+			"           [--org ORG] [--department DEPT] [--title TITLE] [--manager ID] [--assistant ID]\n" +
+			"           [--prefix P] [--given G] [--middle M] [--family F] [--suffix S] [--nickname N]\n" +
+			"           [--tag|--add-tag|--remove-tag TAG]...\n" +
+			"           [--birthday DATE] [--anniversary DATE] [--event|--add-event LABEL:DATE]... [--remove-event LABEL]...\n" +
+			"           [--notes TEXT] [--add-note TEXT] [--field NAME=VALUE]...",
+			"change some fields of a contact, an empty value clears a work detail or part of the name", cmdEdit},
+		{"fields", "", "list the custom fields declared in the -fields schema file", cmdFields},
+		{"log", "ID KIND [TEXT...] [--at TIME]", "log a call, meeting or message with a contact; KIND is called, met, emailed,\n" +
+			"           messaged or another word, TIME is YYYY-MM-DD [HH:MM] and defaults to now", cmdLog},
+		{"history", "ID [--limit N] [--output FORMAT]", "show the interactions logged with a contact, newest first", cmdHistory},
+		{"upcoming", "[--days N] [--output FORMAT]", "list the birthdays, anniversaries and other dates of the next N days (30 by default)", cmdUpcoming},
+		{"export", "FORMAT [FILE] [--id ID]... [--tag TAG]... [--any-tag] [--favorites]",
+			"write the contacts, or those given or with the tags, to FILE or standard output;\n" +
+				"           FORMAT is ics (the yearly dates as an iCalendar feed), vcard (vCard 4.0),\n" +
+				"           google or outlook (CSV files Google Contacts or Outlook import) or json (see schema)", cmdExport},
+		{"import", "FORMAT FILE [--mapping FILE] [--dry-run]", "add the contacts of FILE, - for standard input, merging those already in the book,\n" +
+			"           and report the entries rejected; FORMAT is vcard (vCard 2.1, 3.0 or 4.0), csv (as\n" +
+			"           Google Contacts or Outlook export it, or with the columns given in a --mapping file)\n" +
+			"           or json (checked against the schema)", cmdImport},
+		{"schema", "", "print the JSON Schema of the documents export json writes and import json reads", cmdSchema},
+		{"delete", "ID [--yes]", "delete a contact, --yes skips the confirmation", cmdDelete},
+		{"group", "list | show NAME | create NAME ID... | rename OLD NEW | delete NAME\n" +
+			"           | add NAME [ID...] [--match QUERY] | remove NAME [ID...] [--match QUERY]",
+			"manage groups, the sets of contacts sharing a tag", cmdGroup},
+		{"help", "", "show this help", cmdHelp},
+	}
+}
+
+// errors carrying the exit code the program should end with
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+func usageError(format string, a ...any) error {
+	return &codedError{exitUsage, fmt.Errorf(format, a...)}
+}
+
+func invalidError(err error) error {
+	return &codedError{exitInvalid, err}
+}
+
+// run the subcommand named by args[0] and return the exit code
+func runCommand(args []string) int {
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:])
+		if err == nil {
+			return exitOK
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintf(os.Stderr, "contacts %s: %v\n", cmd.name, err)
+		return exitCode(err)
+	}
+	fmt.Fprintf(os.Stderr, "contacts: unknown command %q\n", args[0])
+	usage()
+	return exitUsage
+}
+
+// pick the exit code matching err
+func exitCode(err error) int {
+	var exit *codedError
+	switch {
+	case errors.As(err, &exit):
+		return exit.code
+	case errors.Is(err, book.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, book.ErrConflict):
+		return exitConflict
+	}
+	return exitFailure
+}
+
+// print the program usage
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage:")
+	fmt.Fprintln(out, "  contacts [flags]                   interactive menu")
+	fmt.Fprintln(out, "  contacts [flags] COMMAND [ARGS]    run one command")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintln(out, strings.TrimSpace(fmt.Sprintf("  %-8s %s", cmd.name, cmd.args)))
+		fmt.Fprintf(out, "           %s\n", cmd.summary)
+	}
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// new flag set for a subcommand that reports errors instead of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("contacts "+name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parse flags and positional arguments in any order
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, &codedError{exitUsage, err}
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// a flag that may be given several times
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, "; ") }
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// find the one contact an ID (or an unambiguous start of one) refers to
+func resolveContact(id string) (book.Contact, error) {
+	contacts, err := store.List()
+	if err != nil {
+		return book.Contact{}, err
+	}
+	var matches []book.Contact
+	for _, contact := range contacts {
+		if contact.ID == id {
+			return contact, nil
+		}
+		if strings.HasPrefix(contact.ID, strings.ToLower(id)) {
+			matches = append(matches, contact)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return book.Contact{}, fmt.Errorf("%w: %s", book.ErrNotFound, id)
+	case 1:
+		return matches[0], nil
+	}
+	return book.Contact{}, usageError("id %q matches %d contacts, give more characters", id, len(matches))
+}
+
+// the name part flags shared by add and edit
+type nameFlags map[string]*string
+
+func addNameFlags(fs *flag.FlagSet) nameFlags {
+	return nameFlags{
+		"prefix":   fs.String("prefix", "", "name prefix, such as Dr"),
+		"given":    fs.String("given", "", "given name"),
+		"middle":   fs.String("middle", "", "middle names"),
+		"family":   fs.String("family", "", "family name, with particles such as van der"),
+		"suffix":   fs.String("suffix", "", "name suffix, such as Jr or III"),
+		"nickname": fs.String("nickname", "", "nickname"),
+	}
+}
+
+// whether any name part was given on the command line
+func (f nameFlags) given(changed map[string]bool) bool {
+	for name := range f {
+		if changed[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// replace the name parts given on the command line
+func (f nameFlags) apply(contact *book.Contact, changed map[string]bool) error {
+	if !f.given(changed) {
+		return nil
+	}
+	parts := contact.NameParts
+	if parts.IsZero() {
+		parts = book.ParseName(contact.Name)
+	}
+	for name, field := range map[string]*string{
+		"prefix": &parts.Prefix, "given": &parts.Given, "middle": &parts.Middle,
+		"family": &parts.Family, "suffix": &parts.Suffix, "nickname": &parts.Nickname,
+	} {
+		if changed[name] {
+			*field = strings.TrimSpace(*f[name])
+		}
+	}
+	if parts.Format(book.GivenFirst) == "" {
+		return invalidError(book.ErrEmptyName)
+	}
+	contact.SetNameParts(parts)
+	return nil
+}
+
+// add the --since filter to list and search; the returned function reads it
+func sinceFlag(fs *flag.FlagSet) func() (time.Time, error) {
+	since := fs.String("since", "", "only contacts added or changed on or after DATE, as YYYY-MM-DD [HH:MM]")
+	return func() (time.Time, error) {
+		if *since == "" {
+			return time.Time{}, nil
+		}
+		return parseTime(*since)
+	}
+}
+
+// add the --tag and --any-tag filters to list and search
+func tagFilterFlags(fs *flag.FlagSet) (*listFlag, *bool) {
+	tags := new(listFlag)
+	fs.Var(tags, "tag", "only contacts with this tag, may be repeated; every tag must match unless --any-tag")
+	anyTag := fs.Bool("any-tag", false, "keep contacts with any of the --tag tags instead of all of them")
+	return tags, anyTag
+}
+
+// sort contacts as asked by --sort
+func sortContacts(contacts []book.Contact, by string) error {
+	switch by {
+	case "":
+	case "name":
+		book.SortByName(contacts, nameOrder)
+	case "family":
+		book.SortByName(contacts, book.FamilyFirst)
+	case "contacted":
+		book.SortByLastContacted(contacts)
+	case "updated":
+		book.SortByUpdated(contacts)
+	default:
+		return usageError("unknown sort %q, want name, family, contacted or updated", by)
+	}
+	return nil
+}
+
+// the work detail flags shared by add and edit
+type workFlags struct {
+	org, department, title, manager, assistant *string
+}
+
+func addWorkFlags(fs *flag.FlagSet) workFlags {
+	return workFlags{
+		org:        fs.String("org", "", "organization"),
+		department: fs.String("department", "", "department"),
+		title:      fs.String("title", "", "job title"),
+		manager:    fs.String("manager", "", "ID of the contact's manager"),
+		assistant:  fs.String("assistant", "", "ID of the contact's assistant"),
+	}
+}
+
+// copy the work flags given on the command line to contact
+func (f workFlags) apply(contact *book.Contact, changed map[string]bool) error {
+	if changed["org"] {
+		contact.Organization = strings.TrimSpace(*f.org)
+	}
+	if changed["department"] {
+		contact.Department = strings.TrimSpace(*f.department)
+	}
+	if changed["title"] {
+		contact.Title = strings.TrimSpace(*f.title)
+	}
+	var err error
+	if changed["manager"] {
+		if contact.ManagerID, err = referenceID(*f.manager, contact.ID); err != nil {
+			return err
+		}
+	}
+	if changed["assistant"] {
+		if contact.AssistantID, err = referenceID(*f.assistant, contact.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// full ID of the contact a --manager or --assistant flag names; an empty
+// value clears the reference
+func referenceID(ref, self string) (string, error) {
+	if strings.TrimSpace(ref) == "" {
+		return "", nil
+	}
+	other, err := resolveContact(strings.TrimSpace(ref))
+	if err != nil {
+		return "", err
+	}
+	if other.ID == self {
+		return "", invalidError(errors.New("a contact cannot be its own manager or assistant"))
+	}
+	return other.ID, nil
+}
+
+// the --birthday and --anniversary flags shared by add and edit
+type dateFlags map[string]*string
+
+// the events set by dateFlags, in the order they are added to contacts
+var dateLabels = []string{book.EventBirthday, book.EventAnniversary}
+
+func addDateFlags(fs *flag.FlagSet) dateFlags {
+	return dateFlags{
+		book.EventBirthday:    fs.String("birthday", "", "birthday as YYYY-MM-DD, or --MM-DD without the year"),
+		book.EventAnniversary: fs.String("anniversary", "", "anniversary as YYYY-MM-DD, or --MM-DD without the year"),
+	}
+}
+
+// set the dates given on the command line; an empty value removes the event
+func (f dateFlags) apply(contact *book.Contact, changed map[string]bool) error {
+	for _, label := range dateLabels {
+		if !changed[label] {
+			continue
+		}
+		if strings.TrimSpace(*f[label]) == "" {
+			contact.RemoveEvent(label)
+			continue
+		}
+		date, err := book.ParseDate(*f[label])
+		if err != nil {
+			return invalidError(err)
+		}
+		contact.SetEvent(label, date)
+	}
+	return nil
+}
+
+// set the events given as label:date, one per label
+func setEvents(contact *book.Contact, entries []string) error {
+	for _, entry := range entries {
+		event, err := book.ParseEvent(entry)
+		if err != nil {
+			return invalidError(err)
+		}
+		contact.SetEvent(event.Label, event.Date)
+	}
+	return nil
+}
+
+// names of the flags given on the command line
+func visited(fs *flag.FlagSet) map[string]bool {
+	changed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { changed[f.Name] = true })
+	return changed
+}
+
+// contacts add --name NAME [--email [LABEL:]EMAIL]... [--phone [LABEL:]NUMBER]...
+func cmdAdd(args []string) error {
+	var emails, phones, mobiles, addresses, tagFlags, events listFlag
+	fs := newFlagSet("add")
+	name := fs.String("name", "", "contact name")
+	fs.Var(&emails, "email", "email as [*][label:]address, may be repeated; * marks the primary one")
+	fs.Var(&phones, "phone", "phone as [*][label:]number, may be repeated; * marks the primary one")
+	fs.Var(&mobiles, "mobile", "mobile number, same as --phone mobile:NUMBER")
+	fs.Var(&addresses, "address", "postal address as [label:]street|city|region|postal code|country, may be repeated")
+	work := addWorkFlags(fs)
+	names := addNameFlags(fs)
+	fs.Var(&tagFlags, "tag", "tag putting the contact in a group, may be repeated")
+	dates := addDateFlags(fs)
+	fs.Var(&events, "event", "date celebrated every year, as label:YYYY-MM-DD or label:--MM-DD, may be repeated")
+	notes := fs.String("notes", "", "free text notes, line breaks included")
+	var fields listFlag
+	fs.Var(&fields, "field", "custom field declared in the schema, as NAME=VALUE, may be repeated")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return usageError("unexpected argument %q", rest[0])
+	}
+	tags, err := splitTags(tagFlags)
+	if err != nil {
+		return err
+	}
+	changed := visited(fs)
+	// a name given in parts only is first put together for NewContact
+	fromParts := *name == "" && names.given(changed)
+	if fromParts {
+		*name = book.NameParts{Prefix: *names["prefix"], Given: *names["given"], Middle: *names["middle"],
+			Family: *names["family"], Suffix: *names["suffix"]}.Format(book.GivenFirst)
+	}
+	// mobiles come first, the single number older versions kept was one
+	for i := range mobiles {
+		mobiles[i] = book.LabelMobile + ":" + mobiles[i]
+	}
+	phones = append(mobiles, phones...)
+
+	contact, err := book.NewContact(*name, emails, phones, region)
+	if err != nil {
+		return invalidError(err)
+	}
+	for _, entry := range addresses {
+		address, err := book.ParseAddress(entry)
+		if err != nil {
+			return invalidError(err)
+		}
+		contact.Addresses = append(contact.Addresses, address)
+	}
+	if err := work.apply(&contact, changed); err != nil {
+		return err
+	}
+	if fromParts {
+		// the parts as given, not as NewContact split them
+		contact.Name, contact.NameParts = "", book.NameParts{}
+	}
+	if err := names.apply(&contact, changed); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		contact.AddTag(tag)
+	}
+	if err := dates.apply(&contact, changed); err != nil {
+		return err
+	}
+	if err := setEvents(&contact, events); err != nil {
+		return err
+	}
+	contact.Notes = book.NormalizeNotes(*notes)
+	if err := setFields(&contact, fields); err != nil {
+		return err
+	}
+	contact, err = store.Add(contact)
+	if err != nil {
+		return err
+	}
+	fmt.Println(contact.ID)
+	return nil
+}
+
+// contacts list [--org ORG] [--group-by org] [--output FORMAT]
+func cmdList(args []string) error {
+	fs := newFlagSet("list")
+	org := fs.String("org", "", "only contacts whose organization contains ORG")
+	groupBy := fs.String("group-by", "", "group the contacts, by: org")
+	since := sinceFlag(fs)
+	sortBy := fs.String("sort", "", "sort the contacts by name, family name, the last time they were contacted or changed: name, family, contacted, updated")
+	tagFlags, anyTag := tagFilterFlags(fs)
+	favorites := fs.Bool("favorites", false, "only favorite contacts")
+	format := outputFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return usageError("unexpected argument %q", rest[0])
+	}
+	tags, err := splitTags(*tagFlags)
+	if err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	if *groupBy != "" && *groupBy != "org" {
+		return usageError("unknown grouping %q, want org", *groupBy)
+	}
+	from, err := since()
+	if err != nil {
+		return err
+	}
+	contacts, err := store.Query(book.Query{Organization: *org, Tags: tags, AnyTag: *anyTag, Since: from, Favorites: *favorites})
+	if err != nil {
+		return err
+	}
+	if err := sortContacts(contacts, *sortBy); err != nil {
+		return err
+	}
+	book.FavoritesFirst(contacts)
+	if *groupBy == "org" {
+		// the table gets a heading per organization, other formats the
+		// contacts in the same order
+		if *format == "table" {
+			return writeGroupedTable(os.Stdout, contacts)
+		}
+		var ordered []book.Contact
+		for _, group := range book.GroupByOrganization(contacts) {
+			ordered = append(ordered, group.Contacts...)
+		}
+		contacts = ordered
+	}
+	return writeContacts(os.Stdout, *format, contacts)
+}
+
+// contacts search QUERY [--org ORG] [--output FORMAT]
+func cmdSearch(args []string) error {
+	fs := newFlagSet("search")
+	org := fs.String("org", "", "only contacts whose organization contains ORG")
+	since := sinceFlag(fs)
+	sortBy := fs.String("sort", "", "sort the contacts by name, family name, the last time they were contacted or changed: name, family, contacted, updated")
+	tagFlags, anyTag := tagFilterFlags(fs)
+	favorites := fs.Bool("favorites", false, "only favorite contacts")
+	format := outputFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usageError("expected one search query")
+	}
+	tags, err := splitTags(*tagFlags)
+	if err != nil {
+		return err
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	from, err := since()
+	if err != nil {
+		return err
+	}
+	contacts, err := store.Query(book.Query{Name: rest[0], Organization: *org, Tags: tags, AnyTag: *anyTag, Since: from, Favorites: *favorites})
+	if err != nil {
+		return err
+	}
+	if err := sortContacts(contacts, *sortBy); err != nil {
+		return err
+	}
+	book.FavoritesFirst(contacts)
+	// machine formats still get an empty document to parse
+	if len(contacts) > 0 || *format != "table" {
+		if err := writeContacts(os.Stdout, *format, contacts); err != nil {
+			return err
+		}
+	}
+	if len(contacts) == 0 {
+		return fmt.Errorf("%w: no contact matches %q", book.ErrNotFound, rest[0])
+	}
+	return nil
+}
+
+// contacts show ID [--changes]
+func cmdShow(args []string) error {
+	fs := newFlagSet("show")
+	changes := fs.Bool("changes", false, "also show when each field last changed")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usageError("expected one contact ID")
+	}
+	contact, err := resolveContact(rest[0])
+	if err != nil {
+		return err
+	}
+	printDetails(contact)
+	if *changes {
+		for _, change := range contact.Changes() {
+			fmt.Printf("┃%-16s changed %s\n", change.Field, formatTime(change.At))
+		}
+	}
+	return nil
+}
+
+// contacts edit ID [--name NAME] [--email ...] [--phone ...]
+func cmdEdit(args []string) error {
+	var emails, addEmails, removeEmails, phones, addPhones, removePhones listFlag
+	var addresses, addAddresses, removeAddresses listFlag
+	var setTags, addTags, removeTags listFlag
+	var events, addEvents, removeEvents listFlag
+	fs := newFlagSet("edit")
+	name := fs.String("name", "", "new name")
+	fs.Var(&emails, "email", "replace every email, as [*][label:]address, may be repeated")
+	fs.Var(&addEmails, "add-email", "add an email, as [*][label:]address, may be repeated")
+	fs.Var(&removeEmails, "remove-email", "remove the email with this address, may be repeated")
+	primaryEmail := fs.String("primary-email", "", "make the email with this address the primary one")
+	fs.Var(&phones, "phone", "replace every phone, as [*][label:]number, may be repeated")
+	fs.Var(&addPhones, "add-phone", "add a phone, as [*][label:]number, may be repeated")
+	fs.Var(&removePhones, "remove-phone", "remove the phone with this number, may be repeated")
+	primaryPhone := fs.String("primary-phone", "", "make the phone with this number the primary one")
+	fs.Var(&addresses, "address", "replace every address, as [label:]street|city|region|postal code|country, may be repeated")
+	fs.Var(&addAddresses, "add-address", "add an address, as [label:]street|city|region|postal code|country, may be repeated")
+	fs.Var(&removeAddresses, "remove-address", "remove the address with this label, may be repeated")
+	work := addWorkFlags(fs)
+	names := addNameFlags(fs)
+	fs.Var(&setTags, "tag", "replace every tag, may be repeated")
+	fs.Var(&addTags, "add-tag", "add a tag, may be repeated")
+	fs.Var(&removeTags, "remove-tag", "remove a tag, may be repeated")
+	dates := addDateFlags(fs)
+	fs.Var(&events, "event", "replace every date, as label:YYYY-MM-DD or label:--MM-DD, may be repeated")
+	fs.Var(&addEvents, "add-event", "add or change a date, as label:YYYY-MM-DD or label:--MM-DD, may be repeated")
+	fs.Var(&removeEvents, "remove-event", "remove the date with this label, may be repeated")
+	notes := fs.String("notes", "", "replace the notes, an empty value clears them")
+	var addNotes listFlag
+	fs.Var(&addNotes, "add-note", "append a line to the notes, may be repeated")
+	var fields listFlag
+	fs.Var(&fields, "field", "set a custom field declared in the schema, as NAME=VALUE, may be repeated; an empty VALUE clears it")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usageError("expected one contact ID")
+	}
+	changed := visited(fs)
+	if len(changed) == 0 {
+		return usageError("nothing to change, see contacts help")
+	}
+
+	contact, err := resolveContact(rest[0])
+	if err != nil {
+		return err
+	}
+	if changed["name"] {
+		value := strings.TrimSpace(*name)
+		if value == "" {
+			return invalidError(book.ErrEmptyName)
+		}
+		contact.SetName(value)
+	}
+	if err := names.apply(&contact, changed); err != nil {
+		return err
+	}
+
+	// emails: replace, remove, add, then pick the primary one
+	if changed["email"] {
+		contact.Emails = nil
+	}
+	for _, address := range removeEmails {
+		i := indexEmail(contact.Emails, address)
+		if i < 0 {
+			return fmt.Errorf("%w: no email %s", book.ErrNotFound, address)
+		}
+		contact.Emails = slices.Concat(contact.Emails[:i], contact.Emails[i+1:])
+	}
+	for _, entry := range append(emails, addEmails...) {
+		email, err := book.ParseEmail(entry)
+		if err != nil {
+			return invalidError(err)
+		}
+		contact.AddEmail(email)
+	}
+	if changed["primary-email"] {
+		i := indexEmail(contact.Emails, *primaryEmail)
+		if i < 0 {
+			return fmt.Errorf("%w: no email %s", book.ErrNotFound, *primaryEmail)
+		}
+		for j := range contact.Emails {
+			contact.Emails[j].Primary = j == i
+		}
+	}
+
+	// phones: same steps
+	if changed["phone"] {
+		contact.Phones = nil
+	}
+	for _, number := range removePhones {
+		i := indexPhone(contact.Phones, number)
+		if i < 0 {
+			return fmt.Errorf("%w: no phone %s", book.ErrNotFound, number)
+		}
+		contact.Phones = slices.Concat(contact.Phones[:i], contact.Phones[i+1:])
+	}
+	for _, entry := range append(phones, addPhones...) {
+		phone, err := book.ParsePhoneEntry(entry, region)
+		if err != nil {
+			return invalidError(err)
+		}
+		contact.AddPhone(phone)
+	}
+	if changed["primary-phone"] {
+		i := indexPhone(contact.Phones, *primaryPhone)
+		if i < 0 {
+			return fmt.Errorf("%w: no phone %s", book.ErrNotFound, *primaryPhone)
+		}
+		for j := range contact.Phones {
+			contact.Phones[j].Primary = j == i
+		}
+	}
+
+	// addresses: replace, remove, then add
+	if changed["address"] {
+		contact.Addresses = nil
+	}
+	for _, label := range removeAddresses {
+		i := indexAddress(contact.Addresses, label)
+		if i < 0 {
+			return fmt.Errorf("%w: no address %s", book.ErrNotFound, label)
+		}
+		contact.Addresses = slices.Concat(contact.Addresses[:i], contact.Addresses[i+1:])
+	}
+	for _, entry := range append(addresses, addAddresses...) {
+		address, err := book.ParseAddress(entry)
+		if err != nil {
+			return invalidError(err)
+		}
+		contact.Addresses = append(contact.Addresses, address)
+	}
+	if err := work.apply(&contact, changed); err != nil {
+		return err
+	}
+
+	// tags: replace, remove, then add
+	if changed["tag"] {
+		contact.Tags = nil
+	}
+	remove, err := splitTags(removeTags)
+	if err != nil {
+		return err
+	}
+	for _, tag := range remove {
+		if !contact.RemoveTag(tag) {
+			return fmt.Errorf("%w: no tag %s", book.ErrNotFound, tag)
+		}
+	}
+	add, err := splitTags(append(setTags, addTags...))
+	if err != nil {
+		return err
+	}
+	for _, tag := range add {
+		contact.AddTag(tag)
+	}
+
+	// events: replace, remove, then add
+	if changed["event"] {
+		contact.Events = nil
+	}
+	for _, label := range removeEvents {
+		if !contact.RemoveEvent(label) {
+			return fmt.Errorf("%w: no date %s", book.ErrNotFound, label)
+		}
+	}
+	if err := setEvents(&contact, append(events, addEvents...)); err != nil {
+		return err
+	}
+	if err := dates.apply(&contact, changed); err != nil {
+		return err
+	}
+
+	if changed["notes"] {
+		contact.Notes = book.NormalizeNotes(*notes)
+	}
+	for _, note := range addNotes {
+		contact.AddNote(note)
+	}
+	if err := setFields(&contact, fields); err != nil {
+		return err
+	}
+
+	contact.Normalize()
+	if err := store.Update(contact); err != nil {
+		return err
+	}
+	// the store stamps the change, so show the contact as it was saved
+	saved, err := store.Get(contact.ID)
+	if err != nil {
+		return err
+	}
+	printDetails(saved)
+	return nil
+}
+
+// position of the email with this address, ignoring case, or -1
+func indexEmail(emails []book.Email, address string) int {
+	for i, email := range emails {
+		if strings.EqualFold(email.Address, strings.TrimSpace(address)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// position of the phone with this number, however it is typed, or -1
+func indexPhone(phones []book.Phone, number string) int {
+	e164, err := book.ParsePhone(number, region)
+	for i, phone := range phones {
+		if phone.Number == strings.TrimSpace(number) || err == nil && phone.Number == e164 {
+			return i
+		}
+	}
+	return -1
+}
+
+// position of the address with this label, or written exactly this way, or -1
+func indexAddress(addresses []book.Address, label string) int {
+	label = strings.TrimSpace(label)
+	for i, address := range addresses {
+		if strings.EqualFold(address.Label, label) || address.String() == label {
+			return i
+		}
+	}
+	return -1
+}
+
+// contacts favorite ID
+func cmdFavorite(args []string) error {
+	rest, err := parseArgs(newFlagSet("favorite"), args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usageError("expected one contact ID")
+	}
+	contact, err := resolveContact(rest[0])
+	if err != nil {
+		return err
+	}
+	contact.Favorite = !contact.Favorite
+	if err := store.Update(contact); err != nil {
+		return err
+	}
+	fmt.Println(describeFavorite(contact))
+	return nil
+}
+
+// contacts delete ID [--yes]
+func cmdDelete(args []string) error {
+	fs := newFlagSet("delete")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usageError("expected one contact ID")
+	}
+
+	contact, err := resolveContact(rest[0])
+	if err != nil {
+		return err
+	}
+	if !*yes {
+		printContact(contact)
+		fmt.Println("Delete this contact? (y/n):")
+		answer, err := stdin.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if strings.TrimSpace(answer) != "y" {
+			return &codedError{exitAborted, errors.New("not confirmed, nothing deleted")}
+		}
+	}
+	if err := store.Delete(contact); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s\n", contact.ID)
+	return nil
+}
+
+// contacts help
+func cmdHelp(args []string) error {
+	flag.CommandLine.SetOutput(os.Stdout)
+	usage()
+	return nil
+}
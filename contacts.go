@@ -2,316 +2,585 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"regexp"
 	"strings"
+	"time"
 
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
+	"contact-book/book"
 )
 
 const (
-	displayMenu = "(1). Add Contact \n(2). List Contacts \n(3). Search \n(4). Delete Contact \n(5). Edit Contact \n(6). Exit"
-	filePath    = "contacts.txt"
+	displayMenu = "(1). Add Contact \n(2). List Contacts \n(3). Search \n(4). Delete Contact \n(5). Edit Contact \n(6). Show Contact \n(7). Exit"
 )
 
-type Contact struct {
-	Name   string
-	Email  string
-	Mobile string
-}
+// how to type several labeled emails or phones in one prompt
+const entriesHint = "(several allowed, e.g. work:value; home:value, start one with * to make it primary)"
 
-// create new contacts
-func addContact() {
-	var name string
-	var email string
-	var mobile string
-	var err error
-	var file *os.File
-	pattern := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
-	var zeroCheck bool
+// where the contacts live, chosen with -store and -file
+var store book.Store
 
-	fmt.Println("Adding New Contact")
+// the country of numbers typed without a country code, how numbers are
+// shown, the order of names and the file declaring the custom fields
+var (
+	region     = book.DefaultRegion
+	phoneStyle = book.International
+	nameOrder  = book.GivenFirst
+	schemaPath = book.DefaultSchemaPath
+)
 
-	// name input
-	fmt.Println("Enter the new contact name:")
-	fmt.Println("---------------------------")
-	reader := bufio.NewReader(os.Stdin)
-	name, err = reader.ReadString('\n')
-	name = strings.TrimSpace(name)
-	upperC := cases.Title(language.English)
-	name = upperC.String(name)
-	if err != nil {
-		log.Fatalf("Error reading input %v:\n %v:\n", name, err)
-		return
+// every prompt shares one buffered reader so no typed input gets lost
+var stdin = bufio.NewReader(os.Stdin)
+
+// read one trimmed line from the user
+func readLine(reader *bufio.Reader) string {
+	input, err := reader.ReadString('\n')
+	if err != nil && input == "" {
+		log.Fatalf("Error reading input %v\n", err)
 	}
+	return strings.TrimSpace(input)
+}
 
-	// email input
-	fmt.Println("Enter the new contact email:")
-	fmt.Println("---------------------------")
-	reader = bufio.NewReader(os.Stdin)
+// keep asking until every email on the line is valid
+func readEmails(reader *bufio.Reader) []book.Email {
 	for {
-		email, err = reader.ReadString('\n')
-		email = strings.TrimSpace(email)
-		match, _ := regexp.MatchString(pattern, email)
-		if !match {
-			fmt.Println("Please Enter a valid email address!")
-			fmt.Println("--------------------------------")
-		} else {
-			break
+		var emails []book.Email
+		var err error
+		for _, entry := range book.SplitEntries(readLine(reader)) {
+			var email book.Email
+			if email, err = book.ParseEmail(entry); err != nil {
+				break
+			}
+			emails = append(emails, email)
+		}
+		if err == nil && len(emails) > 0 {
+			return emails
 		}
+		fmt.Println("Please Enter a valid email address!")
+		fmt.Println("--------------------------------")
 	}
-	if err != nil {
-		log.Fatalf("Error reading input %v:\n\n%v:\n", email, err)
-		return
+}
+
+// keep asking until every phone number on the line is valid
+func readPhones(reader *bufio.Reader) []book.Phone {
+	for {
+		var phones []book.Phone
+		var err error
+		for _, entry := range book.SplitEntries(readLine(reader)) {
+			var phone book.Phone
+			if phone, err = book.ParsePhoneEntry(entry, region); err != nil {
+				break
+			}
+			phones = append(phones, phone)
+		}
+		if err == nil && len(phones) > 0 {
+			return phones
+		}
+		if err == nil {
+			err = book.ErrInvalidPhone
+		}
+		fmt.Printf("Please Enter a valid mobile number (%v)\n", err)
+		fmt.Println("----------------------------------------------------")
 	}
+}
 
-	// mobile input
-	fmt.Println("Enter the new contact mobile:")
-	fmt.Println("---------------------------")
-	reader = bufio.NewReader(os.Stdin)
+// ask for postal addresses one field at a time until the user has no more
+func readAddresses(reader *bufio.Reader) []book.Address {
+	var addresses []book.Address
+	question := "Add a postal address? (y/n):"
 	for {
-		mobile, err = reader.ReadString('\n')
-		mobile = strings.TrimSpace(mobile)
-		zeroCheck = strings.HasPrefix(mobile, "0")
-		if len(mobile) != 10 || !zeroCheck {
-			fmt.Println("Please Enter a valid mobile number, Start with '0'")
-			fmt.Println("----------------------------------------------------")
+		fmt.Println(question)
+		fmt.Println("---------------------------")
+		if readLine(reader) != "y" {
+			return addresses
+		}
+		fields := make([]string, 6)
+		for i, prompt := range []string{"Label (home, work, ...)", "Street", "City", "Region / state", "Postal code", "Country"} {
+			fmt.Printf("%s:\n", prompt)
+			fields[i] = readLine(reader)
+		}
+		address, err := book.NewAddress(fields[0], fields[1], fields[2], fields[3], fields[4], fields[5])
+		if err != nil {
+			fmt.Printf("Address not added (%v)\n", err)
 		} else {
-			mobile = strings.Replace(mobile, "0", "+971", 1)
-			break
+			addresses = append(addresses, address)
 		}
+		question = "Add another postal address? (y/n):"
 	}
-	if err != nil {
-		log.Fatalf("Error reading input %v:\n\n%v:\n", mobile, err)
-		return
+}
+
+// keep asking until every tag on the line is valid, an empty line is no tags
+func readTags(reader *bufio.Reader) []string {
+	for {
+		var tags []string
+		var err error
+		for _, entry := range strings.FieldsFunc(readLine(reader), func(r rune) bool { return r == ',' || r == ';' }) {
+			var tag string
+			if tag, err = book.NormalizeTag(entry); err != nil {
+				break
+			}
+			tags = append(tags, tag)
+		}
+		if err == nil {
+			return tags
+		}
+		fmt.Printf("Please Enter valid tags (%v)\n", err)
 	}
-	// adding new contact
-	newContact := Contact{
-		Name:   name,
-		Email:  email,
-		Mobile: mobile,
+}
+
+// ask for the birthday, the anniversary and any other yearly date; each
+// question is repeated until it gets a valid answer or none
+func readEvents(reader *bufio.Reader) []book.Event {
+	var contact book.Contact
+	for _, label := range []string{book.EventBirthday, book.EventAnniversary} {
+		fmt.Printf("%s (YYYY-MM-DD, or --MM-DD without the year, Enter to skip):\n", capitalizeLabel(label))
+		for {
+			input := readLine(reader)
+			if input == "" {
+				break
+			}
+			date, err := book.ParseDate(input)
+			if err == nil {
+				contact.SetEvent(label, date)
+				break
+			}
+			fmt.Printf("Please Enter a valid date (%v)\n", err)
+		}
 	}
+	fmt.Println("Other dates as label:date, separated by ';' (optional):")
+	for {
+		var err error
+		events := contact.Events
+		for _, entry := range book.SplitEntries(readLine(reader)) {
+			var event book.Event
+			if event, err = book.ParseEvent(entry); err != nil {
+				break
+			}
+			events = append(events, event)
+		}
+		if err == nil {
+			return events
+		}
+		fmt.Printf("Please Enter valid dates (%v)\n", err)
+	}
+}
 
-	// creare the file
-	file, err = os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+// read lines of free text until a line holding a single dot
+func readNotes(reader *bufio.Reader) string {
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "." || err != nil && line == "" {
+			return book.NormalizeNotes(strings.Join(lines, "\n"))
+		}
+		lines = append(lines, line)
+	}
+}
+
+// ask what happened and log it as happening now
+func readInteraction(reader *bufio.Reader) (book.Interaction, error) {
+	fmt.Printf("What happened? (%s, %s, %s, %s or another word):\n",
+		book.InteractionCalled, book.InteractionMet, book.InteractionEmailed, book.InteractionMessaged)
+	kind := readLine(reader)
+	fmt.Println("A short note about it (optional):")
+	return book.NewInteraction(time.Now(), kind, readLine(reader))
+}
+
+// a time in the local zone, to the minute
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// an entry of the interaction log as a sentence
+func describeInteraction(entry book.Interaction) string {
+	s := entry.Kind + " on " + formatTime(entry.At)
+	if entry.Text != "" {
+		s += ": " + entry.Text
+	}
+	return s
+}
+
+// whether a contact is a favorite, as a sentence
+func describeFavorite(contact book.Contact) string {
+	if contact.Favorite {
+		return fmt.Sprintf("★ %s is a favorite", contact.DisplayName(nameOrder))
+	}
+	return fmt.Sprintf("%s is no longer a favorite", contact.DisplayName(nameOrder))
+}
+
+// a label as the start of a sentence: "birthday" is shown as "Birthday"
+func capitalizeLabel(label string) string {
+	if label == "" {
+		return label
+	}
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// return the value of an environment variable or a fallback
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// load all the contacts or stop the program
+func loadContacts() []book.Contact {
+	contacts, err := store.List()
 	if err != nil {
-		log.Fatalf("Error opening file %v\n:", err)
-		return
+		log.Fatalf("Error reading contacts %v\n", err)
 	}
-	defer func() {
-		err = file.Close()
-		if err != nil {
-			log.Fatalf("Error closing file %v\n:", err)
+	return contacts
+}
+
+// find contacts by ID (or the start of one) and fall back to the name
+func findContacts(input string) []book.Contact {
+	var byID, byName []book.Contact
+	for _, contact := range loadContacts() {
+		if input != "" && strings.HasPrefix(contact.ID, strings.ToLower(input)) {
+			byID = append(byID, contact)
 		}
-	}()
+		if contact.Matches(input) {
+			byName = append(byName, contact)
+		}
+	}
+	if len(byID) == 1 {
+		return byID
+	}
+	return byName
+}
 
-	// write on the file
-	_, err = fmt.Fprintf(file, "%s,%s,%s\n", newContact.Name, newContact.Email, newContact.Mobile)
-	if err != nil {
-		log.Fatalf("Error writing to file %v\n:", err)
-		return
+// print one contact row
+func printContact(contact book.Contact) {
+	fmt.Print(tableRow(contact))
+}
+
+// show a stored phone number the way the user asked for
+func formatPhone(number string) string {
+	return book.FormatPhone(number, phoneStyle, region)
+}
+
+// print the table header and one row per contact
+func printTable(contacts []book.Contact) {
+	writeTable(os.Stdout, contacts)
+}
+
+// print every field of one contact
+func printDetails(contact book.Contact) {
+	fmt.Printf("┃ID: %s\n┃Name: %s\n", contact.ID, contact.DisplayName(nameOrder))
+	for _, field := range nameFields(&contact.NameParts) {
+		if *field.value != "" {
+			fmt.Printf("┃  %s: %s\n", field.prompt, *field.value)
+		}
+	}
+	for _, email := range contact.Emails {
+		fmt.Printf("┃Email: %s%s\n", email.Address, describeEntry(email.Label, email.Primary))
+	}
+	for _, phone := range contact.Phones {
+		fmt.Printf("┃Phone: %s%s\n", formatPhone(phone.Number), describeEntry(phone.Label, phone.Primary))
+	}
+	for _, address := range contact.Addresses {
+		fmt.Printf("┃Address: %s%s\n", strings.Join(address.Lines(), ", "), describeEntry(address.Label, false))
+	}
+	for _, field := range [][2]string{
+		{"Organization", contact.Organization},
+		{"Department", contact.Department},
+		{"Title", contact.Title},
+		{"Manager", describeReference(contact.ManagerID)},
+		{"Assistant", describeReference(contact.AssistantID)},
+	} {
+		if field[1] != "" {
+			fmt.Printf("┃%s: %s\n", field[0], field[1])
+		}
+	}
+	if len(contact.Tags) > 0 {
+		fmt.Printf("┃Groups: %s\n", strings.Join(contact.Tags, ", "))
+	}
+	if contact.Favorite {
+		fmt.Println("┃Favorite: ★")
+	}
+	for _, event := range contact.Events {
+		fmt.Printf("┃%s: %s\n", capitalizeLabel(event.Label), event.Date)
+	}
+	for _, field := range contactFields(contact) {
+		fmt.Printf("┃%s: %s\n", field[0], field[1])
+	}
+	if contact.Notes != "" {
+		fmt.Printf("┃Notes:\n┃  %s\n", strings.ReplaceAll(contact.Notes, "\n", "\n┃  "))
+	}
+	if n := len(contact.Interactions); n > 0 {
+		fmt.Printf("┃Last contacted: %s (%d logged, see history)\n", describeInteraction(contact.Interactions[n-1]), n)
+	}
+	if !contact.Created.IsZero() {
+		fmt.Printf("┃Created: %s\n", formatTime(contact.Created))
+	}
+	if !contact.Updated.IsZero() {
+		fmt.Printf("┃Updated: %s\n", formatTime(contact.Updated))
 	}
-	fmt.Println("Successfully saved input")
-	fmt.Printf("┃Name: %s\n┃Email: %s\n┃Mobile: %s\n", name, email, mobile)
 	fmt.Println("----------------")
 }
 
-// List all the contents
-func listContact() {
-	var err error
-	var file *os.File
-
-	file, err = os.OpenFile(filePath, os.O_RDONLY, 0o644)
+// the name and short ID of the contact a manager or assistant ID points to
+func describeReference(id string) string {
+	if id == "" {
+		return ""
+	}
+	other, err := store.Get(id)
 	if err != nil {
-		log.Fatalf("Error Opening the file %v\n", err)
+		return id
 	}
+	return fmt.Sprintf("%s (%s)", other.Name, other.ShortID())
+}
 
-	defer func() {
-		err = file.Close()
-		if err != nil {
-			log.Fatalf("Error closing file %v\n:", err)
+// the " [value]" shown after a prompt when a field already has a value
+func currentValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return " [" + value + "]"
+}
+
+// a text field asked for by readFields
+type textField struct {
+	prompt string
+	value  *string
+}
+
+// the parts of a structured name, in the order they are asked for
+func nameFields(name *book.NameParts) []textField {
+	return []textField{
+		{"Prefix", &name.Prefix},
+		{"Given name", &name.Given},
+		{"Middle name", &name.Middle},
+		{"Family name", &name.Family},
+		{"Suffix", &name.Suffix},
+		{"Nickname", &name.Nickname},
+	}
+}
+
+// ask for each field, Enter keeps the current value and "-" clears it
+func readFields(reader *bufio.Reader, fields []textField) {
+	for _, field := range fields {
+		fmt.Printf("%s%s:\n", field.prompt, currentValue(*field.value))
+		switch input := readLine(reader); input {
+		case "":
+		case "-":
+			*field.value = ""
+		default:
+			*field.value = input
 		}
-	}()
+	}
+}
 
-	fmt.Println("--- List of Contents ---")
-	scanner := bufio.NewScanner(file)
-	fmt.Println("┃        Name        ┃        Email        ┃        Mobile        ┃")
-	fmt.Println("=====================================================================")
-	for scanner.Scan() {
-		line := scanner.Text()
-		part := strings.Split(line, ",")
-		contact := Contact{
-			Name:   part[0],
-			Email:  part[1],
-			Mobile: part[2],
+// ask for the work details, Enter keeps the current value and "-" clears it
+func readWork(reader *bufio.Reader, contact *book.Contact) {
+	readFields(reader, []textField{
+		{"Organization", &contact.Organization},
+		{"Department", &contact.Department},
+		{"Job title", &contact.Title},
+	})
+	for _, field := range []struct {
+		prompt string
+		id     *string
+	}{
+		{"Manager (name or ID)", &contact.ManagerID},
+		{"Assistant (name or ID)", &contact.AssistantID},
+	} {
+		fmt.Printf("%s%s:\n", field.prompt, currentValue(describeReference(*field.id)))
+		for {
+			input := readLine(reader)
+			if input == "" {
+				break
+			}
+			if input == "-" {
+				*field.id = ""
+				break
+			}
+			matches := findContacts(input)
+			if len(matches) == 1 && matches[0].ID != contact.ID {
+				*field.id = matches[0].ID
+				break
+			}
+			fmt.Printf("%d other contacts match %q, please be more precise:\n", len(matches), input)
 		}
-		fmt.Printf("|%-20s|%-21s|%-20s", contact.Name, contact.Email, contact.Mobile)
-		fmt.Printf("\n")
 	}
-	fmt.Println("---------------------------------------")
 }
 
-// Contacts counter
-func countContact() {
-	var err error
-	var file *os.File
-	counter := 0
+// the "(work, primary)" note shown after an email or phone
+func describeEntry(label string, primary bool) string {
+	var notes []string
+	if label != "" {
+		notes = append(notes, label)
+	}
+	if primary {
+		notes = append(notes, "primary")
+	}
+	if len(notes) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(notes, ", ") + ")"
+}
 
-	file, err = os.OpenFile(filePath, os.O_RDONLY, 0o644)
-	if err != nil {
-		log.Fatalf("Error Opening the file %v\n", err)
+// create new contacts
+func addContact() {
+	reader := stdin
+	fmt.Println("Adding New Contact")
+
+	// name input
+	fmt.Println("Enter the new contact name:")
+	fmt.Println("---------------------------")
+	var name book.Contact
+	name.SetName(readLine(reader))
+
+	// email input
+	fmt.Println("Enter the new contact email:")
+	fmt.Println(entriesHint)
+	fmt.Println("---------------------------")
+	emails := readEmails(reader)
+
+	// mobile input
+	fmt.Println("Enter the new contact mobile:")
+	fmt.Println(entriesHint)
+	fmt.Println("---------------------------")
+	phones := readPhones(reader)
+
+	// postal addresses are optional
+	addresses := readAddresses(reader)
+
+	// so are the work details
+	var work book.Contact
+	fmt.Println("Work details (optional, press Enter to skip):")
+	readWork(reader, &work)
+
+	fmt.Println("Tags putting the contact in groups, separated by commas (optional):")
+	tags := readTags(reader)
+
+	fmt.Println("Dates celebrated every year (optional):")
+	events := readEvents(reader)
+
+	var custom book.Contact
+	if len(schema.Fields) > 0 {
+		fmt.Println("Custom fields (optional, press Enter to skip):")
+		readCustomFields(reader, &custom)
 	}
 
-	defer func() {
-		err = file.Close()
-		if err != nil {
-			log.Fatalf("Error closing file %v\n:", err)
-		}
-	}()
+	// adding new contact
+	newContact := book.Contact{
+		Name:      name.Name,
+		NameParts: name.NameParts,
+		Emails:    emails,
+		Phones:    phones,
+		Addresses: addresses,
+
+		Organization: work.Organization,
+		Department:   work.Department,
+		Title:        work.Title,
+		ManagerID:    work.ManagerID,
+		AssistantID:  work.AssistantID,
+
+		Events: events,
+		Fields: custom.Fields,
+	}
+	for _, tag := range tags {
+		newContact.AddTag(tag)
+	}
+	newContact.Normalize()
+	newContact, err := store.Add(newContact)
+	if err != nil {
+		log.Fatalf("Error writing to file %v\n:", err)
+	}
+	fmt.Println("Successfully saved input")
+	printDetails(newContact)
+}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		counter++
+// List all the contents, grouped by organization once contacts have one
+func listContact() {
+	fmt.Println("--- List of Contents ---")
+	contacts := loadContacts()
+	book.FavoritesFirst(contacts)
+	grouped := false
+	for _, contact := range contacts {
+		grouped = grouped || contact.Organization != ""
 	}
-	fmt.Printf("Contacts available: %d\n", counter)
+	if grouped {
+		writeGroupedTable(os.Stdout, contacts)
+	} else {
+		printTable(contacts)
+	}
+	fmt.Println("---------------------------------------")
+}
+
+// Contacts counter
+func countContact() {
+	fmt.Printf("Contacts available: %d\n", len(loadContacts()))
 	fmt.Println("---------------------------------------")
 }
 
 // Search for contact
 func search() {
-	var err error
-	var file *os.File
 	found := false
-	fmt.Println("Search for contact by name:")
+	fmt.Println("Search for contact by name, email, phone or address:")
 	fmt.Println("---------------------------")
-	reader := bufio.NewReader(os.Stdin)
-	userInput, err := reader.ReadString('\n')
-	userInput = strings.TrimSpace(userInput)
-	file, err = os.OpenFile(filePath, os.O_RDONLY, 0o644)
-	if err != nil {
-		log.Fatalf("Error Opening the file %v\n", err)
-	}
-
-	defer func() {
-		err = file.Close()
-		if err != nil {
-			log.Fatalf("Error closing file %v\n:", err)
-		}
-	}()
+	reader := stdin
+	userInput := readLine(reader)
 
-	scanner := bufio.NewScanner(file)
 	fmt.Printf("Here's all the available contacts for %v\n", userInput)
 	fmt.Println("--------------------------------------------")
-	for scanner.Scan() {
-		line := scanner.Text()
-		part := strings.Split(line, ",")
-		contact := Contact{
-			Name:   part[0],
-			Email:  part[1],
-			Mobile: part[2],
-		}
-		// if strings.EqualFold(contact.Name, userInput) {
-		if strings.Contains(strings.ToLower(contact.Name), strings.ToLower(userInput)) {
-			fmt.Printf("|%-20s|%-21s|%-20s\n", contact.Name, contact.Email, contact.Mobile)
-			found = true
-		}
+	contacts, err := store.Query(book.Query{Name: userInput})
+	if err != nil {
+		log.Fatalf("Error reading contacts %v\n", err)
+	}
+	for _, contact := range contacts {
+		printContact(contact)
+		found = true
 	}
 	if !found {
 		fmt.Printf("there are no contacts by this %v\n", userInput)
 	}
-	if err != nil {
-		log.Fatalf("Error reading input %v:\n\n%v:\n", userInput, err)
-		return
-	}
 	fmt.Println("=====================================================")
 }
 
 // Delete a contact
 func deleteContact() {
-	var err error
-	var file *os.File
-	var allContacts []Contact
-	found := false
-	var remainingContacts []Contact
+	deleted := 0
 
-	fmt.Println("Delete contact, find by name:")
+	fmt.Println("Delete contact, find by name or ID:")
 	fmt.Println("---------------------------")
-	reader := bufio.NewReader(os.Stdin)
-	userInput, err := reader.ReadString('\n')
-	userInput = strings.TrimSpace(userInput)
-	file, err = os.OpenFile(filePath, os.O_RDONLY, 0o644)
-	if err != nil {
-		log.Fatalf("Error Opening the file %v\n", err)
-	}
+	reader := stdin
+	userInput := readLine(reader)
 
-	defer func() {
-		err = file.Close()
-		if err != nil {
-			log.Fatalf("Error closing file %v\n:", err)
-		}
-	}()
-	fmt.Println("--- List of Contents ---")
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		part := strings.Split(line, ",")
-		contact := Contact{
-			Name:   part[0],
-			Email:  part[1],
-			Mobile: part[2],
-		}
-		allContacts = append(allContacts, contact)
-		// fmt.Println(allContacts)
-	}
-	for _, contact := range allContacts {
-		if strings.Contains(strings.ToLower(contact.Name), strings.ToLower(userInput)) {
-			fmt.Printf("|%-20s|%-21s|%-20s\n", contact.Name, contact.Email, contact.Mobile)
-			found = true
-
-			fmt.Println("Delete this contact? (y/n):")
-			fmt.Println("---------------------------")
-			confirm, err := reader.ReadString('\n')
-			confirm = strings.TrimSpace(confirm)
-			if err != nil {
-				log.Fatalf("Error reading input %v:\n\n%v:\n", userInput, err)
-				return
-			}
-			if confirm == "y" {
-				// don't append
-			} else {
-				remainingContacts = append(remainingContacts, contact)
-			}
-		} else {
-			remainingContacts = append(remainingContacts, contact)
-		}
-	}
-	if !found {
+	matches := findContacts(userInput)
+	if len(matches) == 0 {
 		fmt.Printf("there are no contacts by this %v\n", userInput)
-	}
-	if err != nil {
-		log.Fatalf("Error reading input %v:\n\n%v:\n", userInput, err)
-		return
-	}
-	// creare the file
-
-	file, err = os.OpenFile(filePath, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0o644)
-	if err != nil {
-		log.Fatalf("Error opening file %v\n:", err)
 		return
 	}
+	fmt.Println("--- List of Contents ---")
+	for _, contact := range matches {
+		printContact(contact)
 
-	// write on the file
-	for _, contact := range remainingContacts {
-		_, err = fmt.Fprintf(file, "%s,%s,%s\n", contact.Name, contact.Email, contact.Mobile)
-		if err != nil {
-			log.Fatalf("Error writing to file: %v\n", err)
+		fmt.Println("Delete this contact? (y/n):")
+		fmt.Println("---------------------------")
+		if readLine(reader) != "y" {
+			continue
+		}
+		err := store.Delete(contact)
+		if errors.Is(err, book.ErrConflict) {
+			fmt.Println("This contact was changed by another program and was not deleted. Please try again.")
 			return
 		}
+		if err != nil {
+			log.Fatalf("Error deleting contact: %v\n", err)
+		}
+		deleted++
 	}
-	fmt.Println("Successfully updating contacts list")
+	fmt.Printf("Successfully deleted %d contact(s)\n", deleted)
 	fmt.Println("=====================================================")
 }
 
@@ -319,145 +588,173 @@ func deleteContact() {
 
 // Edit contact
 func editContact() {
-	var err error
-	var file *os.File
-	var allContacts []Contact
-
-	found := false
-	fmt.Println("Edit contact, find by name:")
+	fmt.Println("Edit contact, find by name or ID:")
 	fmt.Println("---------------------------")
-	reader := bufio.NewReader(os.Stdin)
-	userInput, err := reader.ReadString('\n')
-	userInput = strings.TrimSpace(userInput)
-	file, err = os.OpenFile(filePath, os.O_RDONLY, 0o644)
-	if err != nil {
-		log.Fatalf("Error Opening the file %v\n", err)
+	reader := stdin
+	userInput := readLine(reader)
+
+	matches := findContacts(userInput)
+	if len(matches) == 0 {
+		fmt.Printf("there are no contacts by this %v\n", userInput)
+		return
 	}
+	for _, contact := range matches {
+		printContact(contact)
 
-	defer func() {
-		err = file.Close()
-		if err != nil {
-			log.Fatalf("Error closing file %v\n:", err)
-		}
-	}()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		part := strings.Split(line, ",")
-		contact := Contact{
-			Name:   part[0],
-			Email:  part[1],
-			Mobile: part[2],
+		fmt.Println("Edit this contact? (y/n):")
+		fmt.Println("---------------------------")
+		if readLine(reader) != "y" {
+			continue
 		}
-		allContacts = append(allContacts, contact)
-		// fmt.Println(allContacts)
-	}
-	for i := range allContacts {
-		if strings.Contains(strings.ToLower(allContacts[i].Name), strings.ToLower(userInput)) {
-			fmt.Printf("|%-20s|%-21s|%-20s\n", allContacts[i].Name, allContacts[i].Email, allContacts[i].Mobile)
-			found = true
-
-			fmt.Println("Edit this contact? (y/n):")
-			fmt.Println("---------------------------")
-			confirm, err := reader.ReadString('\n')
-			confirm = strings.TrimSpace(confirm)
+		fmt.Println("What to edit? (1).Name | (2).Emails | (3).Phones | (4).Addresses | (5).Work | (6).Name parts | (7).Tags | (8).Dates | (9).Notes | (10).Log an interaction | (11).Custom fields | (12).Favorite")
+		fmt.Println("---------------------------")
+		switch readLine(reader) {
+		case "1":
+			fmt.Println("Enter new name:")
+			contact.SetName(readLine(reader))
+		case "2":
+			fmt.Printf("Current emails: %s\n", book.JoinEmails(contact.Emails))
+			fmt.Println("Enter new emails:")
+			fmt.Println(entriesHint)
+			contact.Emails = readEmails(reader)
+		case "3":
+			fmt.Printf("Current phones: %s\n", book.JoinPhones(contact.Phones))
+			fmt.Println("Enter new phones:")
+			fmt.Println(entriesHint)
+			contact.Phones = readPhones(reader)
+		case "4":
+			for _, address := range contact.Addresses {
+				fmt.Printf("Current address: %s\n", strings.Join(address.Lines(), ", "))
+			}
+			fmt.Println("Enter the new addresses, they replace the current ones:")
+			contact.Addresses = readAddresses(reader)
+		case "5":
+			fmt.Println("Enter the work details, Enter keeps a value and - clears it:")
+			readWork(reader, &contact)
+		case "6":
+			fmt.Println("Enter the parts of the name, Enter keeps a value and - clears it:")
+			parts := contact.NameParts
+			if parts.IsZero() {
+				parts = book.ParseName(contact.Name)
+			}
+			readFields(reader, nameFields(&parts))
+			if parts.Format(book.GivenFirst) == "" {
+				fmt.Println("A name needs at least one part, nothing was changed")
+				continue
+			}
+			contact.SetNameParts(parts)
+		case "7":
+			fmt.Printf("Current tags: %s\n", strings.Join(contact.Tags, ", "))
+			fmt.Println("Enter the new tags, separated by commas (empty removes every tag):")
+			contact.Tags = nil
+			for _, tag := range readTags(reader) {
+				contact.AddTag(tag)
+			}
+		case "8":
+			fmt.Printf("Current dates: %s\n", book.JoinEvents(contact.Events))
+			fmt.Println("Enter the new dates, they replace the current ones:")
+			contact.Events = readEvents(reader)
+		case "9":
+			if contact.Notes != "" {
+				fmt.Printf("Current notes:\n%s\n", contact.Notes)
+			}
+			fmt.Println("Enter the new notes, they replace the current ones; end with a line holding a single '.':")
+			contact.Notes = readNotes(reader)
+		case "10":
+			entry, err := readInteraction(reader)
 			if err != nil {
-				log.Fatalf("Error reading input %v:\n\n%v:\n", userInput, err)
-				return
+				fmt.Printf("Nothing logged (%v)\n", err)
+				continue
 			}
-			if confirm == "y" {
-				fmt.Println("What to edit? (1).Name | (2).Email | (3).Mobile")
-				fmt.Println("---------------------------")
-				editChoice, err := reader.ReadString('\n')
-				editChoice = strings.TrimSpace(editChoice)
-				if err != nil {
-					log.Fatalf("Error reading input %v:\n\n%v:\n", userInput, err)
-					return
-				}
-				if editChoice == "1" {
-					fmt.Println("Enter new name:")
-					newName, _ := reader.ReadString('\n')
-					newName = strings.TrimSpace(newName)
-					upperC := cases.Title(language.English)
-					newName = upperC.String(newName)
-					allContacts[i].Name = newName // <-- MODIFY ORIGINAL
-				}
-				if editChoice == "2" {
-					pattern := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
-					fmt.Println("Enter new email:")
-					for {
-						newEmail, _ := reader.ReadString('\n')
-						newEmail = strings.TrimSpace(newEmail)
-						match, _ := regexp.MatchString(pattern, newEmail)
-						if !match {
-							fmt.Println("Please Enter a valid email address!")
-						} else {
-							allContacts[i].Email = newEmail
-							break
-						}
-					}
-				}
-				if editChoice == "3" {
-					fmt.Println("Enter new mobile:")
-					for {
-						newMobile, _ := reader.ReadString('\n')
-						newMobile = strings.TrimSpace(newMobile)
-						zeroCheck := strings.HasPrefix(newMobile, "0")
-						if len(newMobile) != 10 || !zeroCheck {
-							fmt.Println("Please Enter a valid mobile number, Start with '0'")
-						} else {
-							newMobile = strings.Replace(newMobile, "0", "+971", 1)
-							allContacts[i].Mobile = newMobile
-							break
-						}
-					}
-				}
+			contact.LogInteraction(entry)
+		case "11":
+			if len(schema.Fields) == 0 {
+				fmt.Printf("No custom fields are declared in %s\n", schemaPath)
+				continue
 			}
+			fmt.Println("Enter the custom fields, Enter keeps a value and - clears it:")
+			readCustomFields(reader, &contact)
+		case "12":
+			contact.Favorite = !contact.Favorite
+			fmt.Println(describeFavorite(contact))
 		}
+		contact.Normalize()
+		err := store.Update(contact)
+		if errors.Is(err, book.ErrConflict) {
+			fmt.Println("This contact was changed by another program, your edit was not saved. Please try again.")
+			return
+		}
+		if err != nil {
+			log.Fatalf("Error updating contact: %v\n", err)
+		}
+		fmt.Println("Successfully updating contacts list")
 	}
-	if !found {
-		fmt.Printf("there are no contacts by this %v\n", userInput)
-	}
-	if err != nil {
-		log.Fatalf("Error reading input %v:\n\n%v:\n", userInput, err)
-		return
-	}
-	// creare the file
+	fmt.Println("=====================================================")
+}
 
-	file, err = os.OpenFile(filePath, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0o644)
-	if err != nil {
-		log.Fatalf("Error opening file %v\n:", err)
+// Show every detail of a contact
+func showContact() {
+	fmt.Println("Show contact, find by name or ID:")
+	fmt.Println("---------------------------")
+	userInput := readLine(stdin)
+
+	matches := findContacts(userInput)
+	if len(matches) == 0 {
+		fmt.Printf("there are no contacts by this %v\n", userInput)
 		return
 	}
-
-	// write on the file
-	for _, contact := range allContacts {
-		_, err = fmt.Fprintf(file, "%s,%s,%s\n", contact.Name, contact.Email, contact.Mobile)
-		if err != nil {
-			log.Fatalf("Error writing to file: %v\n", err)
-			return
-		}
+	for _, contact := range matches {
+		printDetails(contact)
 	}
-	fmt.Println("Successfully updating contacts list")
-	fmt.Println("=====================================================")
 }
 
 // #############################################################################################
 
 // The application!!
 func main() {
-	var choice string
+	storeKind := flag.String("store", getenv("CONTACTS_STORE", book.KindCSV), "storage backend: csv, json or memory (env CONTACTS_STORE)")
+	storePath := flag.String("file", os.Getenv("CONTACTS_FILE"), "contacts file, defaults to contacts.txt or contacts.json (env CONTACTS_FILE)")
+	flag.StringVar(&region, "region", getenv("CONTACTS_REGION", book.DefaultRegion), "country of numbers typed without a country code, one of "+strings.Join(book.Regions(), " ")+" (env CONTACTS_REGION)")
+	phoneFormat := flag.String("phone-format", getenv("CONTACTS_PHONE_FORMAT", "international"), "how phone numbers are shown: national or international (env CONTACTS_PHONE_FORMAT)")
+	order := flag.String("name-order", getenv("CONTACTS_NAME_ORDER", "given"), `how names are shown: given ("Jan van der Berg") or family ("van der Berg, Jan") (env CONTACTS_NAME_ORDER)`)
+	flag.StringVar(&schemaPath, "fields", getenv("CONTACTS_FIELDS", book.DefaultSchemaPath), "schema file declaring the custom fields (env CONTACTS_FIELDS)")
+	flag.Usage = usage
+	flag.Parse()
+
+	var err error
+	region = strings.ToUpper(region)
+	if !book.ValidRegion(region) {
+		log.Fatalf("Unknown region %q, use one of %s\n", region, strings.Join(book.Regions(), " "))
+	}
+	phoneStyle, err = book.ParsePhoneStyle(*phoneFormat)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	nameOrder, err = book.ParseNameOrder(*order)
+	if err != nil {
+		log.Fatalf("Error: %v\n", err)
+	}
+	store, err = book.Open(*storeKind, *storePath)
+	if err != nil {
+		log.Fatalf("Error opening contacts: %v\n", err)
+	}
+	schema, err = book.LoadSchema(schemaPath)
+	if err != nil {
+		log.Fatalf("Error reading the custom fields: %v\n", err)
+	}
+	addFieldColumns()
+
+	// with a subcommand run it and exit, otherwise show the menu
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
+
 	for {
 		fmt.Println(displayMenu)
 		fmt.Println("=====================")
 		fmt.Println("Please choice from the list:")
 		fmt.Println("---------------------")
-		_, err := fmt.Scanf("%s", &choice)
-		if err != nil {
-			log.Fatalf("Error reading input %v\n:", err)
-			return
-		}
+		choice := readLine(stdin)
 		if choice == "1" {
 			addContact()
 		} else if choice == "2" {
@@ -470,6 +767,8 @@ func main() {
 		} else if choice == "5" {
 			editContact()
 		} else if choice == "6" {
+			showContact()
+		} else if choice == "7" {
 			fmt.Println("Goodbye!")
 			break
 		} else {
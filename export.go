@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"contact-book/book"
+)
+
+// an export format and the function writing contacts in it
+type exporter struct {
+	name  string
+	write func(io.Writer, []book.Contact) error
+}
+
+var exporters = []exporter{
+	{"ics", func(w io.Writer, contacts []book.Contact) error { return book.WriteICS(w, contacts, time.Now()) }},
+	{"vcard", book.WriteVCards},
+	{"google", book.WriteGoogleCSV},
+	{"outlook", book.WriteOutlookCSV},
+	{"json", book.WriteJSON},
+}
+
+func exportFormatNames() string {
+	names := make([]string, len(exporters))
+	for i, e := range exporters {
+		names[i] = e.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// contacts schema
+func cmdSchema(args []string) error {
+	rest, err := parseArgs(newFlagSet("schema"), args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return usageError("unexpected argument %q", rest[0])
+	}
+	_, err = os.Stdout.Write(book.JSONSchema)
+	return err
+}
+
+// contacts export FORMAT [FILE] [--id ID]... [--tag TAG]... [--any-tag] [--favorites]
+func cmdExport(args []string) error {
+	var ids listFlag
+	fs := newFlagSet("export")
+	fs.Var(&ids, "id", "export only the contact with this ID, may be repeated")
+	tagFlags, anyTag := tagFilterFlags(fs)
+	favorites := fs.Bool("favorites", false, "only favorite contacts")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 || len(rest) > 2 {
+		return usageError("expected an export format (%s) and optionally a file", exportFormatNames())
+	}
+	var export *exporter
+	for i := range exporters {
+		if exporters[i].name == strings.ToLower(rest[0]) {
+			export = &exporters[i]
+		}
+	}
+	if export == nil {
+		return usageError("unknown export format %q, want one of %s", rest[0], exportFormatNames())
+	}
+	tags, err := splitTags(*tagFlags)
+	if err != nil {
+		return err
+	}
+	var contacts []book.Contact
+	if len(ids) > 0 {
+		if len(tags) > 0 || *favorites {
+			return usageError("--id cannot be combined with --tag or --favorites")
+		}
+		for _, id := range ids {
+			contact, err := resolveContact(id)
+			if err != nil {
+				return err
+			}
+			contacts = append(contacts, contact)
+		}
+	} else if contacts, err = store.Query(book.Query{Tags: tags, AnyTag: *anyTag, Favorites: *favorites}); err != nil {
+		return err
+	}
+	if len(rest) == 1 || rest[1] == "-" {
+		return export.write(os.Stdout, contacts)
+	}
+	file, err := os.Create(rest[1])
+	if err != nil {
+		return err
+	}
+	if err := export.write(file, contacts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
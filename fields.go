@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+
+	"contact-book/book"
+)
+
+// the custom fields declared in the schema file chosen with -fields
+var schema book.Schema
+
+// show every declared custom field as an extra column of the table and tsv
+// outputs
+func addFieldColumns() {
+	for _, f := range schema.Fields {
+		name := f.Name
+		columns = append(columns, column{f.Heading(), max(len([]rune(f.Heading())), 12),
+			func(c book.Contact) string { return c.Field(name) }, nil})
+	}
+}
+
+// the type of a field as shown to users, with the values of an enum
+func describeFieldType(f book.FieldDef) string {
+	if f.Type == book.FieldEnum {
+		return fmt.Sprintf("%s: %s", f.Type, strings.Join(f.Values, ", "))
+	}
+	return string(f.Type)
+}
+
+// set the custom fields given as NAME=VALUE; an empty value clears the field
+func setFields(contact *book.Contact, entries []string) error {
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return usageError("custom field %q is not NAME=VALUE", entry)
+		}
+		if err := schema.Set(contact, name, value); err != nil {
+			return invalidError(err)
+		}
+	}
+	return nil
+}
+
+// the custom fields of a contact, declared ones first in schema order and
+// then those the schema no longer declares
+func contactFields(contact book.Contact) [][2]string {
+	var fields [][2]string
+	for _, f := range schema.Fields {
+		if value := contact.Field(f.Name); value != "" {
+			fields = append(fields, [2]string{f.Heading(), value})
+		}
+	}
+	var undeclared []string
+	for name := range contact.Fields {
+		if _, ok := schema.Field(name); !ok {
+			undeclared = append(undeclared, name)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, name := range undeclared {
+		fields = append(fields, [2]string{name, contact.Fields[name]})
+	}
+	return fields
+}
+
+// ask for every declared custom field, Enter keeps a value and - clears it
+func readCustomFields(reader *bufio.Reader, contact *book.Contact) {
+	for _, f := range schema.Fields {
+		for {
+			fmt.Printf("%s (%s)%s:\n", f.Heading(), describeFieldType(f), currentValue(contact.Field(f.Name)))
+			input := readLine(reader)
+			if input == "" {
+				break
+			}
+			if input == "-" {
+				input = ""
+			}
+			err := schema.Set(contact, f.Name, input)
+			if err == nil {
+				break
+			}
+			fmt.Printf("Please Enter a valid value (%v)\n", err)
+		}
+	}
+}
+
+// contacts fields
+func cmdFields(args []string) error {
+	rest, err := parseArgs(newFlagSet("fields"), args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return usageError("unexpected argument %q", rest[0])
+	}
+	if len(schema.Fields) == 0 {
+		fmt.Printf("No custom fields, declare them in %s\n", schemaPath)
+		return nil
+	}
+	for _, f := range schema.Fields {
+		title := ""
+		if f.Title != "" {
+			title = fmt.Sprintf(" (%s)", f.Title)
+		}
+		fmt.Printf("%s%s: %s\n", f.Name, title, describeFieldType(f))
+	}
+	return nil
+}
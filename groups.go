@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"contact-book/book"
+)
+
+// the group actions, each receiving its arguments without the action name
+var groupActions = []command{
+	{"list", "", "list every group and its number of members", groupList},
+	{"show", "NAME [--output FORMAT]", "list the members of a group", groupShow},
+	{"create", "NAME ID...", "create a group with its first members", groupCreate},
+	{"rename", "OLD NEW", "rename a group", groupRename},
+	{"delete", "NAME", "delete a group, its members are kept", groupDelete},
+	{"add", "NAME [ID...] [--match QUERY]", "add contacts to a group", groupAdd},
+	{"remove", "NAME [ID...] [--match QUERY]", "remove contacts from a group", groupRemove},
+}
+
+// contacts group ACTION [ARGS]
+func cmdGroup(args []string) error {
+	if len(args) == 0 {
+		return usageError("expected a group action: %s", groupActionNames())
+	}
+	for _, action := range groupActions {
+		if action.name == args[0] {
+			return action.run(args[1:])
+		}
+	}
+	return usageError("unknown group action %q, want one of %s", args[0], groupActionNames())
+}
+
+func groupActionNames() string {
+	names := make([]string, len(groupActions))
+	for i, action := range groupActions {
+		names[i] = action.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// split tag flags, which may each hold several tags separated by commas or
+// semicolons
+func splitTags(values []string) ([]string, error) {
+	var tags []string
+	for _, value := range values {
+		for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+			if strings.TrimSpace(tag) == "" {
+				continue
+			}
+			tag, err := book.NormalizeTag(tag)
+			if err != nil {
+				return nil, invalidError(err)
+			}
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// the group called name, as spelled by its members
+func findGroup(contacts []book.Contact, name string) (string, error) {
+	for _, group := range book.Tags(contacts) {
+		if strings.EqualFold(group.Tag, name) {
+			return group.Tag, nil
+		}
+	}
+	return "", noGroupError(name)
+}
+
+func noGroupError(name string) error {
+	return &codedError{exitNotFound, fmt.Errorf("no group %q", name)}
+}
+
+// the contacts named by IDs (or starts of them) and by --match
+func groupMembers(ids []string, match string) ([]book.Contact, error) {
+	var members []book.Contact
+	seen := map[string]bool{}
+	for _, id := range ids {
+		contact, err := resolveContact(id)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[contact.ID] {
+			seen[contact.ID] = true
+			members = append(members, contact)
+		}
+	}
+	if match != "" {
+		found, err := store.Query(book.Query{Name: match})
+		if err != nil {
+			return nil, err
+		}
+		for _, contact := range found {
+			if !seen[contact.ID] {
+				seen[contact.ID] = true
+				members = append(members, contact)
+			}
+		}
+	}
+	if len(members) == 0 {
+		return nil, usageError("expected contact IDs or --match QUERY")
+	}
+	return members, nil
+}
+
+// contacts group list
+func groupList(args []string) error {
+	rest, err := parseArgs(newFlagSet("group list"), args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return usageError("unexpected argument %q", rest[0])
+	}
+	contacts, err := store.List()
+	if err != nil {
+		return err
+	}
+	for _, group := range book.Tags(contacts) {
+		fmt.Printf("%s (%d)\n", group.Tag, group.Count)
+	}
+	return nil
+}
+
+// contacts group show NAME [--output FORMAT]
+func groupShow(args []string) error {
+	fs := newFlagSet("group show")
+	format := outputFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usageError("expected one group name")
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	contacts, err := store.Query(book.Query{Tags: rest})
+	if err != nil {
+		return err
+	}
+	if len(contacts) == 0 {
+		return noGroupError(rest[0])
+	}
+	return writeContacts(os.Stdout, *format, contacts)
+}
+
+// contacts group create NAME ID...
+func groupCreate(args []string) error {
+	rest, err := parseArgs(newFlagSet("group create"), args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 2 {
+		return usageError("expected a group name and the IDs of its first members")
+	}
+	name, err := book.NormalizeTag(rest[0])
+	if err != nil {
+		return invalidError(err)
+	}
+	contacts, err := store.List()
+	if err != nil {
+		return err
+	}
+	if existing, err := findGroup(contacts, name); err == nil {
+		return invalidError(fmt.Errorf("group %q already exists", existing))
+	}
+	members, err := groupMembers(rest[1:], "")
+	if err != nil {
+		return err
+	}
+	for i := range members {
+		members[i].AddTag(name)
+	}
+	if err := store.UpdateMany(members); err != nil {
+		return err
+	}
+	fmt.Printf("Created %s with %d member(s)\n", name, len(members))
+	return nil
+}
+
+// contacts group rename OLD NEW
+func groupRename(args []string) error {
+	rest, err := parseArgs(newFlagSet("group rename"), args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 2 {
+		return usageError("expected the current and the new group name")
+	}
+	name, err := book.NormalizeTag(rest[1])
+	if err != nil {
+		return invalidError(err)
+	}
+	contacts, err := store.List()
+	if err != nil {
+		return err
+	}
+	old, err := findGroup(contacts, rest[0])
+	if err != nil {
+		return err
+	}
+	var changed []book.Contact
+	for _, contact := range contacts {
+		if contact.RenameTag(old, name) {
+			changed = append(changed, contact)
+		}
+	}
+	if err := store.UpdateMany(changed); err != nil {
+		return err
+	}
+	fmt.Printf("Renamed %s to %s (%d member(s))\n", old, name, len(changed))
+	return nil
+}
+
+// contacts group delete NAME
+func groupDelete(args []string) error {
+	rest, err := parseArgs(newFlagSet("group delete"), args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usageError("expected one group name")
+	}
+	contacts, err := store.List()
+	if err != nil {
+		return err
+	}
+	name, err := findGroup(contacts, rest[0])
+	if err != nil {
+		return err
+	}
+	var changed []book.Contact
+	for _, contact := range contacts {
+		if contact.RemoveTag(name) {
+			changed = append(changed, contact)
+		}
+	}
+	if err := store.UpdateMany(changed); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s, %d contact(s) left it\n", name, len(changed))
+	return nil
+}
+
+// contacts group add NAME [ID...] [--match QUERY]
+func groupAdd(args []string) error {
+	return changeMembers("add", args, (*book.Contact).AddTag)
+}
+
+// contacts group remove NAME [ID...] [--match QUERY]
+func groupRemove(args []string) error {
+	return changeMembers("remove", args, (*book.Contact).RemoveTag)
+}
+
+// add or remove the group tag of every contact named on the command line
+func changeMembers(action string, args []string, change func(*book.Contact, string) bool) error {
+	fs := newFlagSet("group " + action)
+	match := fs.String("match", "", "every contact a search for QUERY finds")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return usageError("expected a group name")
+	}
+	contacts, err := store.List()
+	if err != nil {
+		return err
+	}
+	name, err := findGroup(contacts, rest[0])
+	if err != nil {
+		return err
+	}
+	members, err := groupMembers(rest[1:], *match)
+	if err != nil {
+		return err
+	}
+	var changed []book.Contact
+	for _, contact := range members {
+		if change(&contact, name) {
+			changed = append(changed, contact)
+		}
+	}
+	if err := store.UpdateMany(changed); err != nil {
+		return err
+	}
+	already := "members"
+	if action == "remove" {
+		already = "outside the group"
+	}
+	fmt.Printf("%s: %d contact(s) changed, %d already %s\n", name, len(changed), len(members)-len(changed), already)
+	return nil
+}
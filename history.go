@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"contact-book/book"
+)
+
+// layouts accepted by log --at, read in local time unless they carry a zone
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", time.DateOnly}
+
+// read a time typed on the command line
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, invalidError(fmt.Errorf("invalid time %q, want YYYY-MM-DD [HH:MM]", value))
+}
+
+// contacts log ID KIND [TEXT...] [--at TIME]
+func cmdLog(args []string) error {
+	fs := newFlagSet("log")
+	at := fs.String("at", "", "when it happened, as YYYY-MM-DD [HH:MM]; now by default")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 2 {
+		return usageError("expected a contact ID and what happened: %s, %s, %s, %s or another word",
+			book.InteractionCalled, book.InteractionMet, book.InteractionEmailed, book.InteractionMessaged)
+	}
+	when := time.Now()
+	if *at != "" {
+		if when, err = parseTime(*at); err != nil {
+			return err
+		}
+	}
+	entry, err := book.NewInteraction(when, rest[1], strings.Join(rest[2:], " "))
+	if err != nil {
+		return invalidError(err)
+	}
+	contact, err := resolveContact(rest[0])
+	if err != nil {
+		return err
+	}
+	contact.LogInteraction(entry)
+	if err := store.Update(contact); err != nil {
+		return err
+	}
+	fmt.Printf("Logged: %s %s\n", contact.DisplayName(nameOrder), describeInteraction(entry))
+	return nil
+}
+
+// contacts history ID [--limit N] [--output FORMAT]
+func cmdHistory(args []string) error {
+	fs := newFlagSet("history")
+	limit := fs.Int("limit", 0, "show only the latest N entries")
+	format := outputFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return usageError("expected one contact ID")
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	contact, err := resolveContact(rest[0])
+	if err != nil {
+		return err
+	}
+	// newest first
+	var log []book.Interaction
+	for i := len(contact.Interactions) - 1; i >= 0; i-- {
+		log = append(log, contact.Interactions[i])
+	}
+	if *limit > 0 && len(log) > *limit {
+		log = log[:*limit]
+	}
+	return writeHistory(os.Stdout, *format, contact, log)
+}
+
+// write the log of a contact in the requested format
+func writeHistory(w io.Writer, format string, contact book.Contact, log []book.Interaction) error {
+	if log == nil {
+		log = []book.Interaction{}
+	}
+	switch format {
+	case "table":
+		if len(log) == 0 {
+			_, err := fmt.Fprintf(w, "Nothing logged for %s yet\n", contact.DisplayName(nameOrder))
+			return err
+		}
+		fmt.Fprintf(w, "%s, last contacted %s\n", contact.DisplayName(nameOrder), formatTime(contact.LastContacted()))
+		for _, entry := range log {
+			if _, err := fmt.Fprintf(w, "|%-16s|%-9s|%s\n", formatTime(entry.At), entry.Kind, entry.Text); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv", "tsv":
+		rows := make([][]string, len(log))
+		for i, entry := range log {
+			rows[i] = []string{entry.At.Format(time.RFC3339), entry.Kind, entry.Text}
+		}
+		return writeRecords(w, format, []string{"At", "Kind", "Text"}, rows)
+	}
+	return writeDocuments(w, format, log)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"contact-book/book"
+)
+
+// the options of the import command some formats use
+type importOptions struct {
+	mapping string // the column mapping file of a CSV import
+}
+
+// what an importer read: the contacts, the entries it rejected with the
+// reason and, for the preview, how it understood the file
+type imported struct {
+	contacts []book.Contact
+	rejected []book.Rejected
+	layout   []string
+}
+
+// an import format and the function reading contacts in it
+type importer struct {
+	name string
+	read func(io.Reader, importOptions) (imported, error)
+}
+
+var importers = []importer{
+	{"vcard", readVCards},
+	{"csv", readMappedCSV},
+	{"json", readJSON},
+}
+
+func importFormatNames() string {
+	names := make([]string, len(importers))
+	for i, imp := range importers {
+		names[i] = imp.name
+	}
+	return strings.Join(names, ", ")
+}
+
+func readVCards(r io.Reader, _ importOptions) (imported, error) {
+	contacts, rejected, err := book.ReadVCards(r, region)
+	return imported{contacts: contacts, rejected: rejected}, err
+}
+
+func readJSON(r io.Reader, _ importOptions) (imported, error) {
+	contacts, rejected, err := book.ReadJSONContacts(r, region, schema)
+	return imported{contacts: contacts, rejected: rejected}, err
+}
+
+// read a CSV file in the layout of Google Contacts or Outlook, or in the
+// one described by the mapping file
+func readMappedCSV(r io.Reader, opts importOptions) (imported, error) {
+	var mapping book.CSVMapping
+	if opts.mapping != "" {
+		var err error
+		if mapping, err = book.LoadCSVMapping(opts.mapping); err != nil {
+			return imported{}, err
+		}
+	}
+	result, err := book.ReadMappedCSV(r, mapping, region)
+	if err != nil {
+		return imported{}, err
+	}
+	layout := []string{fmt.Sprintf("Columns (%s layout):", result.Mapping.Layout)}
+	ignored := 0
+	for _, header := range result.Header {
+		if target, ok := result.Mapping.Target(header); ok {
+			layout = append(layout, fmt.Sprintf("  %-28s → %s", header, target))
+		} else {
+			ignored++
+		}
+	}
+	if ignored > 0 {
+		layout = append(layout, fmt.Sprintf("  %d other columns ignored", ignored))
+	}
+	return imported{contacts: result.Contacts, rejected: result.Rejected, layout: layout}, nil
+}
+
+// contacts import FORMAT FILE [--mapping FILE] [--dry-run]
+func cmdImport(args []string) error {
+	fs := newFlagSet("import")
+	var opts importOptions
+	fs.StringVar(&opts.mapping, "mapping", "", "JSON file telling which field each column of a CSV file fills in")
+	dryRun := fs.Bool("dry-run", false, "preview what would be imported without saving anything")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 2 {
+		return usageError("expected an import format (%s) and a file, - for standard input", importFormatNames())
+	}
+	var imp *importer
+	for i := range importers {
+		if importers[i].name == strings.ToLower(rest[0]) {
+			imp = &importers[i]
+		}
+	}
+	if imp == nil {
+		return usageError("unknown import format %q, want one of %s", rest[0], importFormatNames())
+	}
+	if opts.mapping != "" && imp.name != "csv" {
+		return usageError("--mapping only applies to csv imports")
+	}
+	var r io.Reader = os.Stdin
+	if rest[1] != "-" {
+		file, err := os.Open(rest[1])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	result, err := imp.read(r, opts)
+	if err != nil {
+		return invalidError(err)
+	}
+
+	// contacts already in the book, or earlier in the file, are merged
+	// rather than added twice
+	existing, err := store.List()
+	if err != nil {
+		return err
+	}
+	all := append([]book.Contact(nil), existing...)
+	changed := map[int]bool{}
+	var report []string
+	added, merged, known := 0, 0, 0
+	for _, c := range result.contacts {
+		i := book.FindDuplicate(all, c)
+		switch {
+		case i < 0:
+			all = append(all, c)
+			added++
+			report = append(report, "  new     "+c.DisplayName(nameOrder))
+		case all[i].Merge(c):
+			changed[i] = true
+			merged++
+			report = append(report, fmt.Sprintf("  merged  %s into %s", c.DisplayName(nameOrder), describeTarget(all[i])))
+		default:
+			known++
+			report = append(report, fmt.Sprintf("  known   %s, as %s", c.DisplayName(nameOrder), describeTarget(all[i])))
+		}
+	}
+
+	if *dryRun {
+		for _, line := range result.layout {
+			fmt.Println(line)
+		}
+		if len(result.contacts) > 0 {
+			if err := writeContacts(os.Stdout, "table", result.contacts); err != nil {
+				return err
+			}
+		}
+		fmt.Print("Would import: ")
+	} else {
+		var updates []book.Contact
+		for i := range existing {
+			if changed[i] {
+				updates = append(updates, all[i])
+			}
+		}
+		// a single change, so that a failure leaves the book as it was
+		if len(updates) > 0 || added > 0 {
+			if _, err := store.Apply(updates, all[len(existing):]); err != nil {
+				return err
+			}
+		}
+		fmt.Print("Imported: ")
+	}
+	fmt.Printf("%d new, %d merged into contacts of the book, %d already in it, %d rejected\n",
+		added, merged, known, len(result.rejected))
+	for _, line := range report {
+		fmt.Println(line)
+	}
+	if len(result.rejected) == 0 {
+		return nil
+	}
+	fmt.Println("Rejected:")
+	for _, r := range result.rejected {
+		fmt.Printf("  %s\n", r)
+	}
+	total := len(result.contacts) + len(result.rejected)
+	return invalidError(fmt.Errorf("%d of %d entries rejected", len(result.rejected), total))
+}
+
+// the contact an imported one was merged into, by short ID and name; one
+// added by the same import has no ID yet
+func describeTarget(c book.Contact) string {
+	if c.ID == "" {
+		return c.DisplayName(nameOrder) + " (new)"
+	}
+	return c.ShortID() + " " + c.DisplayName(nameOrder)
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"contact-book/book"
+)
+
+// formats accepted by --output
+var outputFormats = []string{"table", "json", "ndjson", "csv", "tsv", "yaml"}
+
+// a column of the table and tsv outputs; the table shows value, tsv shows
+// full when it is set and value otherwise
+type column struct {
+	title string
+	width int
+	value func(book.Contact) string
+	full  func(book.Contact) string
+}
+
+var columns = []column{
+	{"★", 1, func(c book.Contact) string { return star(c.Favorite) },
+		func(c book.Contact) string { return fmt.Sprint(c.Favorite) }},
+	{"ID", 8, book.Contact.ShortID, func(c book.Contact) string { return c.ID }},
+	{"Name", 20, func(c book.Contact) string { return c.DisplayName(nameOrder) }, nil},
+	{"Email", 21, func(c book.Contact) string { return withMore(c.PrimaryEmail(), len(c.Emails)) },
+		func(c book.Contact) string { return book.JoinEmails(c.Emails) }},
+	{"Phone", 20, func(c book.Contact) string { return withMore(formatPhone(c.PrimaryPhone()), len(c.Phones)) },
+		func(c book.Contact) string { return book.JoinPhones(c.Phones) }},
+	{"Organization", 16, func(c book.Contact) string { return c.Organization }, nil},
+}
+
+// the mark of a favorite contact
+func star(favorite bool) string {
+	if favorite {
+		return "★"
+	}
+	return ""
+}
+
+// the primary entry, with a note when the contact has more
+func withMore(primary string, count int) string {
+	if count > 1 {
+		return fmt.Sprintf("%s (+%d)", primary, count-1)
+	}
+	return primary
+}
+
+// add the --output flag (and its -o shorthand) to a subcommand
+func outputFlag(fs *flag.FlagSet) *string {
+	format := new(string)
+	usage := "output format: " + strings.Join(outputFormats, ", ")
+	fs.StringVar(format, "output", "table", usage)
+	fs.StringVar(format, "o", "table", "shorthand for --output")
+	return format
+}
+
+// check the format before doing any work
+func validateFormat(format string) error {
+	for _, f := range outputFormats {
+		if f == format {
+			return nil
+		}
+	}
+	return usageError("unknown output format %q, want one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// write contacts in the requested format
+func writeContacts(w io.Writer, format string, contacts []book.Contact) error {
+	if contacts == nil {
+		contacts = []book.Contact{}
+	}
+	switch format {
+	case "table":
+		return writeTable(w, contacts)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(contacts)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		for _, contact := range contacts {
+			if err := enc.Encode(contact); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		return book.WriteCSV(w, contacts)
+	case "tsv":
+		return writeTSV(w, contacts)
+	case "yaml":
+		return writeYAML(w, contacts)
+	}
+	return validateFormat(format)
+}
+
+// write the records of a report other than the contacts themselves in one
+// of the json, ndjson and yaml formats
+func writeDocuments[T any](w io.Writer, format string, items []T) error {
+	if items == nil {
+		items = []T{}
+	}
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(items)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		for _, item := range items {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "yaml":
+		return writeYAML(w, items)
+	}
+	return validateFormat(format)
+}
+
+// write rows under a header row as csv, or as tsv
+func writeRecords(w io.Writer, format string, header []string, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if format == "tsv" {
+		cw.Comma = '\t'
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// the fixed-width table people read in the terminal
+func writeTable(w io.Writer, contacts []book.Contact) error {
+	if err := writeTableHeader(w); err != nil {
+		return err
+	}
+	for _, contact := range contacts {
+		if _, err := io.WriteString(w, tableRow(contact)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// the table with the contacts of each organization under its own heading
+func writeGroupedTable(w io.Writer, contacts []book.Contact) error {
+	if err := writeTableHeader(w); err != nil {
+		return err
+	}
+	for _, group := range book.GroupByOrganization(contacts) {
+		name := group.Organization
+		if name == "" {
+			name = "No organization"
+		}
+		if _, err := fmt.Fprintf(w, "── %s (%d)\n", name, len(group.Contacts)); err != nil {
+			return err
+		}
+		for _, contact := range group.Contacts {
+			if _, err := io.WriteString(w, tableRow(contact)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeTableHeader(w io.Writer) error {
+	var header, rule strings.Builder
+	for _, col := range columns {
+		header.WriteString("┃" + center(col.title, col.width))
+		rule.WriteString(strings.Repeat("=", col.width+1))
+	}
+	header.WriteString("┃")
+	rule.WriteString("=")
+	_, err := fmt.Fprintf(w, "%s\n%s\n", header.String(), rule.String())
+	return err
+}
+
+// one line of the table
+func tableRow(contact book.Contact) string {
+	var row strings.Builder
+	for _, col := range columns {
+		fmt.Fprintf(&row, "┃%-*s", col.width, col.value(contact))
+	}
+	row.WriteString("┃\n")
+	return row.String()
+}
+
+// pad text with spaces on both sides to fill width
+func center(text string, width int) string {
+	pad := width - len([]rune(text))
+	if pad <= 0 {
+		return text
+	}
+	return strings.Repeat(" ", pad/2) + text + strings.Repeat(" ", pad-pad/2)
+}
+
+// tab-separated values with a header row; tabs and line breaks inside
+// values are escaped so every contact stays on one line
+func writeTSV(w io.Writer, contacts []book.Contact) error {
+	escape := strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+	titles := make([]string, len(columns))
+	for i, col := range columns {
+		titles[i] = col.title
+	}
+	values := func(c book.Contact) []string {
+		fields := make([]string, len(columns))
+		for i, col := range columns {
+			value := col.value
+			if col.full != nil {
+				value = col.full
+			}
+			fields[i] = escape.Replace(value(c))
+		}
+		return fields
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(titles, "\t")); err != nil {
+		return err
+	}
+	for _, contact := range contacts {
+		if _, err := fmt.Fprintln(w, strings.Join(values(contact), "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// YAML is produced from the JSON encoding so both always carry the same
+// fields in the same order
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := decodeNode(dec)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	emitYAML(&buf, node, 0)
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// a JSON value with object keys kept in order
+type node struct {
+	scalar string // rendered YAML scalar, when not a container
+	keys   []string
+	values []node
+	isMap  bool
+	isList bool
+}
+
+func decodeNode(dec *json.Decoder) (node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return node{}, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		n := node{isMap: t == '{', isList: t == '['}
+		for dec.More() {
+			if n.isMap {
+				key, err := dec.Token()
+				if err != nil {
+					return node{}, err
+				}
+				n.keys = append(n.keys, fmt.Sprint(key))
+			}
+			value, err := decodeNode(dec)
+			if err != nil {
+				return node{}, err
+			}
+			n.values = append(n.values, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return node{}, err
+		}
+		return n, nil
+	case string:
+		return node{scalar: yamlString(t)}, nil
+	case json.Number:
+		return node{scalar: t.String()}, nil
+	case bool:
+		return node{scalar: strconv.FormatBool(t)}, nil
+	}
+	return node{scalar: "null"}, nil
+}
+
+func emitYAML(buf *bytes.Buffer, n node, indent int) {
+	pad := strings.Repeat(" ", indent)
+	switch {
+	case n.isMap && len(n.values) == 0:
+		buf.WriteString(pad + "{}\n")
+	case n.isList && len(n.values) == 0:
+		buf.WriteString(pad + "[]\n")
+	case n.isMap:
+		for i, key := range n.keys {
+			value := n.values[i]
+			if value.isMap || value.isList {
+				if len(value.values) == 0 {
+					fmt.Fprintf(buf, "%s%s: %s", pad, yamlString(key), emptyContainer(value))
+					continue
+				}
+				fmt.Fprintf(buf, "%s%s:\n", pad, yamlString(key))
+				emitYAML(buf, value, indent+2)
+				continue
+			}
+			fmt.Fprintf(buf, "%s%s: %s\n", pad, yamlString(key), value.scalar)
+		}
+	case n.isList:
+		for _, item := range n.values {
+			if !item.isMap && !item.isList || len(item.values) == 0 {
+				if item.isMap || item.isList {
+					fmt.Fprintf(buf, "%s- %s", pad, emptyContainer(item))
+					continue
+				}
+				fmt.Fprintf(buf, "%s- %s\n", pad, item.scalar)
+				continue
+			}
+			// the first line of the item goes right after the dash
+			var inner bytes.Buffer
+			emitYAML(&inner, item, indent+2)
+			text := inner.String()
+			buf.WriteString(pad + "- " + text[indent+2:])
+		}
+	default:
+		buf.WriteString(pad + n.scalar + "\n")
+	}
+}
+
+func emptyContainer(n node) string {
+	if n.isMap {
+		return "{}\n"
+	}
+	return "[]\n"
+}
+
+// plain scalar when it reads back as the same string, double-quoted otherwise
+func yamlString(s string) string {
+	if s == "" || strings.TrimSpace(s) != s || strings.ContainsAny(s, "\n\r\t\\\"") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") ||
+		strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'%@`0123456789+.") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// failingWriter refuses every write, as a closed pipe or a full disk does
+type failingWriter struct{}
+
+var errWrite = errors.New("no space left on device")
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestWriteRecords(t *testing.T) {
+	rows := [][]string{{"Jane Doe", "jane@example.com"}, {"Doe, John", "john@example.com"}}
+	for format, want := range map[string]string{
+		"csv": "Name,Email\nJane Doe,jane@example.com\n\"Doe, John\",john@example.com\n",
+		"tsv": "Name\tEmail\nJane Doe\tjane@example.com\nDoe, John\tjohn@example.com\n",
+	} {
+		var buf bytes.Buffer
+		if err := writeRecords(&buf, format, []string{"Name", "Email"}, rows); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if buf.String() != want {
+			t.Errorf("%s: wrote %q, want %q", format, buf.String(), want)
+		}
+		if err := writeRecords(failingWriter{}, format, []string{"Name", "Email"}, rows); !errors.Is(err, errWrite) {
+			t.Errorf("%s: writeRecords to a failing writer = %v, want %v", format, err, errWrite)
+		}
+	}
+}
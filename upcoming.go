@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"contact-book/book"
+)
+
+// one line of the upcoming report as the machine formats carry it
+type upcomingEvent struct {
+	Date  string `json:"date"`
+	Days  int    `json:"days"`
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Event string `json:"event"`
+	Since string `json:"since"`
+	Years int    `json:"years,omitempty"`
+}
+
+// contacts upcoming [--days N] [--output FORMAT]
+func cmdUpcoming(args []string) error {
+	fs := newFlagSet("upcoming")
+	days := fs.Int("days", 30, "how many days ahead to look, today included")
+	format := outputFlag(fs)
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return usageError("unexpected argument %q", rest[0])
+	}
+	if *days < 0 {
+		return usageError("--days cannot be negative")
+	}
+	if err := validateFormat(*format); err != nil {
+		return err
+	}
+	contacts, err := store.List()
+	if err != nil {
+		return err
+	}
+	return writeUpcoming(os.Stdout, *format, book.Upcoming(contacts, time.Now(), *days), *days)
+}
+
+// write the upcoming events in the requested format
+func writeUpcoming(w io.Writer, format string, found []book.Occurrence, days int) error {
+	if format == "table" {
+		if len(found) == 0 {
+			_, err := fmt.Fprintf(w, "Nothing coming up in the next %d days\n", days)
+			return err
+		}
+		return writeUpcomingTable(w, found)
+	}
+	events := make([]upcomingEvent, len(found))
+	for i, o := range found {
+		events[i] = upcomingEvent{
+			Date:  o.Date.Format(time.DateOnly),
+			Days:  o.Days,
+			ID:    o.Contact.ID,
+			Name:  o.Contact.DisplayName(nameOrder),
+			Event: o.Event.Label,
+			Since: o.Event.Date.String(),
+			Years: o.Years,
+		}
+	}
+	if format == "csv" || format == "tsv" {
+		rows := make([][]string, len(events))
+		for i, e := range events {
+			years := ""
+			if e.Years > 0 {
+				years = strconv.Itoa(e.Years)
+			}
+			rows[i] = []string{e.Date, strconv.Itoa(e.Days), e.ID, e.Name, e.Event, e.Since, years}
+		}
+		return writeRecords(w, format, []string{"Date", "Days", "ID", "Name", "Event", "Since", "Years"}, rows)
+	}
+	return writeDocuments(w, format, events)
+}
+
+// the upcoming events as a table, with the age turned or the years
+// celebrated when the year of the event is known
+func writeUpcomingTable(w io.Writer, found []book.Occurrence) error {
+	titles := []string{"Date", "When", "Name", "Event", "Years"}
+	widths := []int{10, 11, 20, 12, 5}
+	var header, rule strings.Builder
+	for i, title := range titles {
+		header.WriteString("┃" + center(title, widths[i]))
+		rule.WriteString(strings.Repeat("=", widths[i]+1))
+	}
+	header.WriteString("┃")
+	rule.WriteString("=")
+	if _, err := fmt.Fprintf(w, "%s\n%s\n", header.String(), rule.String()); err != nil {
+		return err
+	}
+	for _, o := range found {
+		years := ""
+		if o.Years > 0 {
+			years = strconv.Itoa(o.Years)
+		}
+		values := []string{o.Date.Format("Mon 02 Jan"), describeDays(o.Days), o.Contact.DisplayName(nameOrder), o.Event.Label, years}
+		var row strings.Builder
+		for i, value := range values {
+			fmt.Fprintf(&row, "┃%-*s", widths[i], value)
+		}
+		row.WriteString("┃")
+		if _, err := fmt.Fprintln(w, row.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// how far away a day is, in words
+func describeDays(days int) string {
+	switch days {
+	case 0:
+		return "today"
+	case 1:
+		return "tomorrow"
+	}
+	return fmt.Sprintf("in %d days", days)
+}